@@ -45,8 +45,10 @@ func main() {
 
 	// Tab setup
 	tabs := container.NewAppTabs(
-		container.NewTabItemWithIcon("Leaderboard", theme.MenuIcon(), ui.DisplayLeaderboard()),
+		container.NewTabItemWithIcon("Leaderboard", theme.MenuIcon(), ui.DisplayLeaderboard(mainWindow)),
 		container.NewTabItemWithIcon("Races", theme.HistoryIcon(), ui.SearchAnimals(mainWindow)),
+		container.NewTabItemWithIcon("Tournaments", theme.GridIcon(), ui.Tournaments(mainWindow)),
+		container.NewTabItemWithIcon("Seasons", theme.ListIcon(), ui.Seasons(mainWindow)),
 	)
 	tabs.SetTabLocation(container.TabLocationTop)
 
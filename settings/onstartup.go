@@ -3,6 +3,8 @@ package settings
 import (
 	"os"
 	"path/filepath"
+	"strings"
+	"hareandtortoise/v2/simulation"
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/container"
@@ -11,12 +13,12 @@ import (
 
 const (
 	folderName         = "data"
-	fileHeader         = "Name,Score,Min Speed,Max Speed,UUID\n"
 	defaultPictureName = "default.png"
 	defaultSoundName   = "cheering.mp3"
 )
 
 var fileName = "animal.simulation"
+var fileHeader = strings.Join(simulation.AnimalHeader, ",") + "\n"
 var filePath = filepath.Join(folderName, fileName)
 var pictureFilepath = filepath.Join(folderName, defaultPictureName)
 var soundFilepath = filepath.Join(folderName, defaultSoundName)
@@ -77,6 +79,14 @@ func CheckAndCreateFolderAndFile(mainWindow fyne.Window) {
 		}
 	}
 	
+	// Bring older animal files up to date with any new columns
+	if migrated, err := simulation.MigrateAnimalFile(filePath); err != nil {
+		showCustomError(err, mainWindow)
+		allChecksPassed = false
+	} else if migrated {
+		dialog.NewInformation("Animal file updated", "The animal file has been updated to the latest format, new columns have been given default values", mainWindow).Show()
+	}
+
 	// Show success message if all checks passed
 	if allChecksPassed {
 		dialog.NewInformation("Filesystem check", "The file and folder check has completed successfully", mainWindow).Show()
@@ -4,9 +4,16 @@ import (
 	"encoding/json"
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
+	"hareandtortoise/v2/simulation"
+	"fmt"
 	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // Settings holds the remote configuration
@@ -14,13 +21,16 @@ type Settings struct {
 	RemoteURL      string `json:"remote_url"`
 	RemoteUsername string `json:"remote_username"`
 	RemotePassword string `json:"remote_password"`
+	TiePolicy      string `json:"tie_policy"`
+	ScoringTables  map[string][]float64 `json:"scoring_tables"` // user defined points tables by name
+	TickMillis     int                  `json:"tick_millis"`    // time between race rounds at 1x, 0 means the default
 }
 
 // settingsFilePath defines where the settings will be saved
 const settingsFilePath = "data/settings.json"
 
-// loadSettings loads the settings from a JSON file
-func loadSettings() (Settings, error) {
+// LoadSettings loads the settings from a JSON file
+func LoadSettings() (Settings, error) {
 	var settings Settings
 	file, err := os.Open(settingsFilePath)
 	if err != nil {
@@ -56,7 +66,7 @@ func ShowSettingsWindow(app fyne.App, version string) {
 	}
 
 	// Load existing settings
-	existingSettings, err := loadSettings()
+	existingSettings, err := LoadSettings()
 	if err != nil {
 		existingSettings = Settings{} // Use zero values if loading fails
 	}
@@ -79,6 +89,84 @@ func ShowSettingsWindow(app fyne.App, version string) {
 	remotePasswordEntry := widget.NewPasswordEntry()
 	remotePasswordEntry.SetText(existingSettings.RemotePassword)
 
+	// Scoring settings
+	tiePolicyLabel := widget.NewLabel("Dead Heat Points:")
+	tiePolicySelect := widget.NewSelect(simulation.TiePolicies, nil)
+	tiePolicySelect.SetSelected(existingSettings.TiePolicy)
+	if tiePolicySelect.Selected == "" {
+		tiePolicySelect.SetSelected(simulation.DefaultTiePolicy)
+	}
+
+	// Race speed setting
+	tickLabel := widget.NewLabel("Round Time at 1x (ms):")
+	tickEntry := widget.NewEntry()
+	tickEntry.SetPlaceHolder(strconv.Itoa(int(simulation.DefaultTick / time.Millisecond)))
+	if existingSettings.TickMillis > 0 {
+		tickEntry.SetText(strconv.Itoa(existingSettings.TickMillis))
+	}
+
+	// User defined points tables, saved straight away so they show up in race setup
+	scoringTablesLabel := widget.NewLabel("Points Tables:")
+	scoringTableSelect := widget.NewSelect(nil, nil)
+	scoringTableSelect.PlaceHolder = "Saved points tables"
+	refreshScoringTables := func() {
+		scoringTableSelect.Options = nil
+		for name := range existingSettings.ScoringTables {
+			scoringTableSelect.Options = append(scoringTableSelect.Options, name)
+		}
+		sort.Strings(scoringTableSelect.Options)
+		scoringTableSelect.ClearSelected()
+		scoringTableSelect.Refresh()
+	}
+	refreshScoringTables()
+
+	scoringTableName := widget.NewEntry()
+	scoringTableName.SetPlaceHolder("Table name")
+	scoringTablePoints := widget.NewEntry()
+	scoringTablePoints.SetPlaceHolder("Points for 1st, 2nd... e.g. 10, 6, 4, 2, 1")
+
+	addScoringTableButton := widget.NewButton("Add Table", func() {
+		name := strings.TrimSpace(scoringTableName.Text)
+		if name == "" {
+			dialog.ShowInformation("Error", "Please enter a name for the points table.", settingsWindow)
+			return
+		}
+		for _, scheme := range simulation.ScoringSchemes(nil) {
+			if scheme.Name == name {
+				dialog.ShowInformation("Error", "That name is used by a built in scoring scheme.", settingsWindow)
+				return
+			}
+		}
+		table, err := simulation.ParsePointsTable(scoringTablePoints.Text)
+		if err != nil {
+			dialog.ShowError(err, settingsWindow)
+			return
+		}
+		if existingSettings.ScoringTables == nil {
+			existingSettings.ScoringTables = make(map[string][]float64)
+		}
+		existingSettings.ScoringTables[name] = table
+		if err := saveSettings(existingSettings); err != nil {
+			dialog.ShowError(err, settingsWindow)
+			return
+		}
+		scoringTableName.SetText("")
+		scoringTablePoints.SetText("")
+		refreshScoringTables()
+	})
+
+	deleteScoringTableButton := widget.NewButton("Delete Table", func() {
+		if scoringTableSelect.Selected == "" {
+			return
+		}
+		delete(existingSettings.ScoringTables, scoringTableSelect.Selected)
+		if err := saveSettings(existingSettings); err != nil {
+			dialog.ShowError(err, settingsWindow)
+			return
+		}
+		refreshScoringTables()
+	})
+
 	// Apply button to apply the selected theme
 	applyButton := widget.NewButton("Apply Theme", func() {
 		selectedTheme := themeSelect.Selected
@@ -89,22 +177,30 @@ func ShowSettingsWindow(app fyne.App, version string) {
 		}
 	})
 
-	// Save button to save remote settings
+	// Save button to save remote and scoring settings
 	saveButton := widget.NewButton("Save", func() {
-		remoteURL := remoteURLEntry.Text
-		remoteUsername := remoteUsernameEntry.Text
-		remotePassword := remotePasswordEntry.Text
-
-		settings := Settings{
-			RemoteURL:      remoteURL,
-			RemoteUsername: remoteUsername,
-			RemotePassword: remotePassword,
+		// start from the saved settings so nothing this window doesn't show gets lost
+		settings := existingSettings
+		settings.RemoteURL = remoteURLEntry.Text
+		settings.RemoteUsername = remoteUsernameEntry.Text
+		settings.RemotePassword = remotePasswordEntry.Text
+		settings.TiePolicy = tiePolicySelect.Selected
+		settings.TickMillis = 0 // blank means the default
+		if tickEntry.Text != "" {
+			tick, err := strconv.Atoi(tickEntry.Text)
+			if err != nil || tick <= 0 {
+				dialog.ShowError(fmt.Errorf("round time must be a whole number of milliseconds above 0"), settingsWindow)
+				return
+			}
+			settings.TickMillis = tick
 		}
 
 		err := saveSettings(settings)
 		if err != nil {
 			println("Error saving settings:", err.Error())
+			return
 		}
+		existingSettings = settings
 	})
 	versionlabel := widget.NewLabel(version)
 	// Layout the UI components
@@ -118,13 +214,22 @@ func ShowSettingsWindow(app fyne.App, version string) {
 		remoteUsernameEntry,
 		remotePasswordLabel,
 		remotePasswordEntry,
+		tiePolicyLabel,
+		tiePolicySelect,
+		tickLabel,
+		tickEntry,
 		saveButton,
+		scoringTablesLabel,
+		container.NewBorder(nil, nil, nil, deleteScoringTableButton, scoringTableSelect),
+		scoringTableName,
+		scoringTablePoints,
+		addScoringTableButton,
 		versionlabel,
 	)
 
 	// Show the window
 	settingsWindow.SetContent(content)
-	settingsWindow.Resize(fyne.NewSize(300, 250))
+	settingsWindow.Resize(fyne.NewSize(350, 550))
 	settingsWindow.CenterOnScreen()
 	settingsWindow.Show()
 }
@@ -3,7 +3,10 @@ package simulation
 import (
 	"github.com/google/uuid"
 	"encoding/csv"
+	"fmt"
+	"math"
 	"os"
+	"strconv"
 )
 // writecsv here again to prevent circular imports
 func WriteCSV(filename string, data [][]string, appendMode bool) error {
@@ -32,11 +35,126 @@ func WriteCSV(filename string, data [][]string, appendMode bool) error {
 
 	return nil
 }
+// AnimalHeader is the header row of animal.simulation
+var AnimalHeader = []string{"Name", "Score", "Min Speed", "Max Speed", "UUID", "Stamina", "Fatigue Rate", "Recovery Rate", "Weather Affinity", "Event Chances", "Strategy", "Script"}
+
+// defaults for animals saved before they had endurance attributes
+const (
+	DefaultStamina     = 100.0
+	DefaultFatigueRate = 1.0
+)
+
+// DefaultRecoveryRate is the endurance an animal gets back per rest, 3x its min speed like the original rule
+func DefaultRecoveryRate(minSpeed float64) float64 {
+	return 3 * minSpeed
+}
+
+// ParseEnduranceAttributes turns the stamina, fatigue rate and recovery rate
+// text into numbers, anything blank or invalid gets the default
+func ParseEnduranceAttributes(stamina string, fatigueRate string, recoveryRate string, minSpeed float64) (float64, float64, float64) {
+	parse := func(text string, fallback float64) float64 {
+		value, err := strconv.ParseFloat(text, 64)
+		if err != nil || value <= 0 {
+			return fallback
+		}
+		return value
+	}
+	return parse(stamina, DefaultStamina), parse(fatigueRate, DefaultFatigueRate), parse(recoveryRate, DefaultRecoveryRate(minSpeed))
+}
+
+// ValidateEndurance checks an animal can always get going again. A full tank
+// and a single rest both have to cover a round at min speed on the most tiring
+// terrain in the weather the animal finds most tiring, or it could rest forever.
+func ValidateEndurance(minSpeed float64, stamina float64, fatigueRate float64, recoveryRate float64, affinities map[string]float64) error {
+	_, terrain := terrainLimits()
+	weather := 0.0
+	animal := Player{Affinities: affinities}
+	for name, effect := range weatherEffects {
+		weather = math.Max(weather, effect.Endurance/animal.Affinity(name))
+	}
+	drain := minSpeed * fatigueRate * terrain * weather
+	if stamina <= drain {
+		return fmt.Errorf("stamina must be more than %.2f, what a round at min speed can cost", drain)
+	}
+	if recoveryRate <= drain {
+		return fmt.Errorf("recovery rate must be more than %.2f, what a round at min speed can cost, or the animal could rest forever", drain)
+	}
+	return nil
+}
+
+// recordEnduranceAttributes reads the endurance columns of an animal record, older records don't have them
+func recordEnduranceAttributes(record []string, minSpeed float64) (float64, float64, float64) {
+	attributes := make([]string, 3)
+	for i := range attributes {
+		if len(record) > 5+i {
+			attributes[i] = record[5+i]
+		}
+	}
+	return ParseEnduranceAttributes(attributes[0], attributes[1], attributes[2], minSpeed)
+}
+
+// AnimalRecord turns a player back into an animal.simulation row
+func AnimalRecord(player Player) []string {
+	return []string{
+		player.Name,
+		strconv.FormatFloat(player.Score, 'f', -1, 64),
+		strconv.FormatFloat(player.MinSpeed, 'f', -1, 64),
+		strconv.FormatFloat(player.MaxSpeed, 'f', -1, 64),
+		player.UUID,
+		strconv.FormatFloat(player.Stamina, 'f', -1, 64),
+		strconv.FormatFloat(player.FatigueRate, 'f', -1, 64),
+		strconv.FormatFloat(player.RecoveryRate, 'f', -1, 64),
+		FormatAffinities(player.Affinities),
+		FormatEventChances(player.EventChances),
+		player.Strategy,
+		player.Script,
+	}
+}
+
+// MigrateAnimalFile adds any missing columns to an older animal.simulation,
+// filling them in with defaults. It reports whether the file needed changing.
+func MigrateAnimalFile(filename string) (bool, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return false, err
+	}
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1 // older rows are shorter
+	records, err := reader.ReadAll()
+	file.Close()
+	if err != nil {
+		return false, err
+	}
+	if len(records) == 0 || len(records[0]) >= len(AnimalHeader) {
+		return false, nil
+	}
+
+	migrated := [][]string{AnimalHeader}
+	for _, record := range records[1:] {
+		if len(record) < 5 {
+			continue // Skip malformed records
+		}
+		minSpeed, _ := strconv.ParseFloat(record[2], 64)
+		stamina, fatigueRate, recoveryRate := recordEnduranceAttributes(record, minSpeed)
+		migrated = append(migrated, []string{
+			record[0], record[1], record[2], record[3], record[4],
+			strconv.FormatFloat(stamina, 'f', -1, 64),
+			strconv.FormatFloat(fatigueRate, 'f', -1, 64),
+			strconv.FormatFloat(recoveryRate, 'f', -1, 64),
+			FormatAffinities(recordAffinities(record)),
+			FormatEventChances(recordEventChances(record)),
+			recordStrategy(record),
+			recordScript(record),
+		})
+	}
+	return true, WriteCSV(filename, migrated, false)
+}
+
 //creates the animal in the database
-func CreateAnimal (name string, minSpeed string, maxSpeed string) {
+func CreateAnimal (name string, minSpeed string, maxSpeed string, stamina string, fatigueRate string, recoveryRate string, affinities string, eventChances string, strategy string, script string) {
 	id := uuid.New().String()
-	data := [][]string{{name,"0",minSpeed,maxSpeed,id}}
+	data := [][]string{{name,"0",minSpeed,maxSpeed,id,stamina,fatigueRate,recoveryRate,affinities,eventChances,strategy,script}}
 	err := WriteCSV("data/animal.simulation", data, true)// true means append
 	if err != nil {
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,619 @@
+package simulation
+//import some stuff
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+)
+
+// how a player's race ended
+const (
+	StatusFinished = "Finished"
+	StatusDNF      = "DNF" // still running when the race was ended early
+)
+
+// race modes
+const (
+	ModeStandard    = "Standard"    // first across the finish line wins
+	ModeTimed       = "Timed"       // run a set number of rounds, whoever got furthest wins
+	ModeElimination = "Elimination" // knock out whoever is last every few rounds until one is left
+	ModeRelay       = "Relay"       // teams share the distance, each animal running one leg
+)
+
+// RaceModes lists the modes for the race setup menu
+var RaceModes = []string{ModeStandard, ModeTimed, ModeElimination, ModeRelay}
+
+// deadHeatTolerance is how close two finish times have to be to count as a dead heat
+const deadHeatTolerance = 1e-9
+
+// a race still going after roundLimitFactor times the rounds its slowest
+// animal needs at min speed is called off, so a field that can't finish
+// doesn't keep the race going forever
+const (
+	roundLimitFactor = 20
+	minRoundLimit    = 1000
+	maxRoundLimit    = 100000
+)
+
+// RaceConfig holds the settings a race is started with
+type RaceConfig struct {
+	TotalDistance int
+	Seed          int64         // the same seed and players always give the same race
+	Telemetry     bool          // record every round so it can be saved alongside the race
+	Model         string        // name of the MovementModel, blank means DefaultMovementModel
+	TiePolicy     string        // how dead heats are scored, blank means DefaultTiePolicy
+	Scoring       ScoringScheme // how places become points, the zero value is linear reversed
+	LeadBonus     bool          // give LeadBonusPoints to whoever led the most rounds
+	Tick          time.Duration // time between rounds in the race window at 1x, 0 means DefaultTick
+	Mode          string        // ModeStandard or ModeTimed, blank means ModeStandard
+	RoundLimit    int           // how many rounds a timed race runs for
+	// how many rounds an elimination race waits between knockouts
+	EliminationInterval int
+	Handicap            string             // how the head starts were worked out, blank means HandicapNone
+	Handicaps           map[string]float64 // head start in metres by animal UUID, see Handicaps
+	Track               Track              // terrain along the race, the zero value is flat
+	Weather             string             // one of Weathers, blank means WeatherSunny
+	RandomEvents        bool               // roll for naps, stumbles, bursts and distractions, see EventTypes
+	MaxRounds           int                // rounds before the race is called off, 0 works it out from the field
+}
+
+// Validate checks the config has everything its mode needs
+func (c RaceConfig) Validate() error {
+	switch c.Mode {
+	case "", ModeStandard, ModeRelay:
+		if c.TotalDistance <= 0 {
+			return fmt.Errorf("please enter a valid race length")
+		}
+	case ModeTimed:
+		if c.RoundLimit <= 0 {
+			return fmt.Errorf("please enter how many rounds the timed race lasts")
+		}
+		if c.TotalDistance < 0 {
+			return fmt.Errorf("invalid race length %d", c.TotalDistance)
+		}
+	case ModeElimination:
+		if c.EliminationInterval <= 0 {
+			return fmt.Errorf("please enter how many rounds go by between knockouts")
+		}
+		if c.TotalDistance < 0 {
+			return fmt.Errorf("invalid race length %d", c.TotalDistance)
+		}
+	default:
+		return fmt.Errorf("unknown race mode %q", c.Mode)
+	}
+	if _, ok := weatherEffects[c.Weather]; c.Weather != "" && !ok {
+		return fmt.Errorf("unknown weather %q", c.Weather)
+	}
+	if c.Handicap != "" && c.Handicap != HandicapNone && c.Mode != "" && c.Mode != ModeStandard {
+		return fmt.Errorf("handicaps need a finish line, use a standard race")
+	}
+	return nil
+}
+
+// NewSeed picks a random seed for races where the user didn't enter one
+func NewSeed() int64 {
+	return time.Now().UnixNano()
+}
+
+// PlayerState is a snapshot of one player at the end of a round
+type PlayerState struct {
+	Distance   float64
+	Endurance  float64
+	Resting    bool
+	Run        float64 // distance run this round, 0 if resting
+	Finished   bool
+	Place      int
+	FinishTime float64 // rounds taken to cross the line, 0 until finished
+	Eliminated bool    // knocked out of an elimination race
+	Leg        int     // which team member is running in a relay, 0 is the first
+	Event      string  // the random event that hit the player this round, EventNap every round of a nap
+}
+
+// RoundState is what Step hands back to whoever is watching the race
+type RoundState struct {
+	Round   int
+	Players []PlayerState
+}
+
+// Race is the headless race engine, it holds everything needed to run a race
+// without any windows so it can be driven by the ui, scripts or batch jobs
+type Race struct {
+	Players       []Player
+	TotalDistance int
+	Seed          int64
+	Model         MovementModel
+	TiePolicy     string
+	Scoring       ScoringScheme
+	LeadBonus     bool
+	Mode          string
+	RoundLimit    int // rounds a timed race runs for, 0 in a standard race
+	// rounds between knockouts in an elimination race, 0 in other races
+	EliminationInterval int
+	Handicap            string // how the head starts were worked out
+	Track               Track  // terrain along the race
+	Weather             string
+	RandomEvents        bool
+	MaxRounds           int          // the race is called off if it is still going after this many rounds
+	CalledOff           bool         // the race hit MaxRounds, anyone still running is a DNF
+	Events              []RaceEvent  // every random event in the order they happened
+	Round               int          // number of rounds run so far
+	Telemetry           []RoundState // every round run, only kept if the config asked for it
+	recording           bool
+	scripts             map[string]*Script // compiled animal scripts by their text
+	rng                 *rand.Rand
+	finishedPlayers     int
+}
+
+// NewRace sets up a race with every player on the start line
+func NewRace(players []Player, config RaceConfig) *Race {
+	for i := range players {
+		// a relay lane starts with its first runner
+		players[i].Splits = nil
+		players[i].Leg = 0
+		if config.Mode == ModeRelay && len(players[i].Team) > 0 {
+			players[i].handOver(0)
+		}
+		fillEnduranceDefaults(&players[i])
+		players[i].Endurance = players[i].Stamina // endurance starts full
+		players[i].Resting = false                // Not resting at start
+		players[i].Handicap = config.Handicaps[players[i].UUID]
+		players[i].Distance = players[i].Handicap // handicapped players start up the track
+		players[i].Finished = false
+		players[i].Place = 0
+		players[i].FinishTime = 0
+		players[i].RoundsLed = 0
+		players[i].Score = 0 // players loaded from the roster come with their lifetime score
+		players[i].Bonus = 0
+		players[i].Status = ""
+		players[i].EliminatedRound = 0
+		players[i].Napping = 0
+	}
+
+	// an unknown model falls back to the default, callers validate names with NewMovementModel
+	model, err := NewMovementModel(config.Model, len(players))
+	if err != nil {
+		model, _ = NewMovementModel(DefaultMovementModel, len(players))
+	}
+
+	scoring := config.Scoring
+	if scoring.Name == "" {
+		scoring = LinearScoring
+	}
+
+	tiePolicy := config.TiePolicy
+	if tiePolicy != TiePolicySplit {
+		tiePolicy = TiePolicyShared
+	}
+
+	// timed and elimination races have no finish line, the track just needs to be long enough to draw
+	mode := config.Mode
+	totalDistance := config.TotalDistance
+	roundLimit, eliminationInterval := 0, 0
+	switch mode {
+	case ModeTimed:
+		roundLimit = config.RoundLimit
+		if totalDistance <= 0 {
+			totalDistance = timedTrackLength(players, roundLimit)
+		}
+	case ModeElimination:
+		eliminationInterval = config.EliminationInterval
+		if totalDistance <= 0 {
+			totalDistance = timedTrackLength(players, eliminationInterval*(len(players)-1))
+		}
+	case ModeRelay:
+		// relays are run to the finish line like a standard race
+	default:
+		mode = ModeStandard
+	}
+
+	handicap := config.Handicap
+	if handicap == "" {
+		handicap = HandicapNone
+	}
+
+	// races from before weather was added were all run in the sun
+	weather := config.Weather
+	if _, ok := weatherEffects[weather]; !ok {
+		weather = WeatherSunny
+	}
+
+	return &Race{
+		Players:       players,
+		TotalDistance: totalDistance,
+		Seed:          config.Seed,
+		Model:         model,
+		TiePolicy:     tiePolicy,
+		Scoring:       scoring,
+		LeadBonus:     config.LeadBonus,
+		Mode:          mode,
+		RoundLimit:    roundLimit,
+
+		EliminationInterval: eliminationInterval,
+		Handicap:            handicap,
+		Track:               config.Track,
+		Weather:             weather,
+		RandomEvents:        config.RandomEvents,
+		MaxRounds:           maxRounds(players, totalDistance, config),
+		recording:           config.Telemetry,
+		rng:                 rand.New(rand.NewSource(config.Seed)),
+	}
+}
+
+// Done reports whether every player has finished
+func (r *Race) Done() bool {
+	return r.finishedPlayers >= len(r.Players)
+}
+
+// Step runs a single round of the race and returns the state after it
+func (r *Race) Step() RoundState {
+	if r.Done() {
+		return r.State()
+	}
+	r.Round++
+
+	runs := make([]float64, len(r.Players))
+	events := make([]string, len(r.Players))
+	var crossed []int // players who crossed the line this round
+	for i := range r.Players {
+		player := &r.Players[i]
+		if player.Finished {
+			continue // Skip finished players
+		}
+
+		if player.Napping > 0 {
+			// still asleep, which is at least restful
+			player.Napping--
+			player.Endurance += r.Model.Recover(r, i)
+			events[i] = EventNap
+			continue
+		}
+
+		if player.Resting {
+			// Recover endurance, never past full, and skip this round
+			player.Endurance = math.Min(player.Stamina, player.Endurance+r.Model.Recover(r, i))
+			player.Resting = false
+			continue
+		}
+
+		// the animal's script or strategy decides how hard it goes. A rest it
+		// chooses stops it like running out does, recovering next round, and
+		// is only taken short of full so it can't rest forever.
+		rest, effort := r.decide(i)
+		if rest && player.Endurance < player.Stamina {
+			player.Resting = true
+			continue
+		}
+
+		event := r.rollEvent(i)
+		if event != "" {
+			events[i] = event
+			r.logEvent(i, event)
+		}
+		switch event {
+		case EventNap:
+			player.Napping = napRounds - 1 // this round is the first of the nap
+			player.Endurance += r.Model.Recover(r, i)
+			continue
+		case EventDistraction:
+			continue // loses the round but doesn't tire
+		}
+
+		// the terrain the animal is on at the start of the round sets its pace and how tiring it is
+		terrain := r.Track.SegmentAt(player.Distance, float64(r.TotalDistance))
+
+		// Deduct endurance based on the distance run this round
+		distanceRun := r.pace(i, effort, r.Model.Run(r, i)) * terrain.Speed * r.weatherSpeed(i) * eventSpeed(event)
+		player.Endurance -= r.Model.Drain(r, i, distanceRun) * terrain.Endurance * r.weatherDrain(i)
+
+		if player.Endurance <= 0 {
+			player.Endurance = 0
+			player.Resting = true
+			continue
+		}
+
+		// Move player if not resting
+		previousDistance := player.Distance
+		player.Distance += distanceRun
+		runs[i] = distanceRun
+
+		if r.Mode == ModeRelay {
+			r.changeOver(i, previousDistance, distanceRun)
+		}
+
+		if r.hasFinishLine() && player.Distance >= float64(r.TotalDistance) {
+			// work out how far through the round they were when they crossed the line
+			fraction := (float64(r.TotalDistance) - previousDistance) / distanceRun
+			player.FinishTime = float64(r.Round-1) + fraction
+			player.Finished = true
+			if r.Mode == ModeRelay {
+				player.Splits = append(player.Splits, player.FinishTime) // the last leg ends at the line
+			}
+			crossed = append(crossed, i)
+		}
+	}
+	r.placeFinishers(crossed)
+	r.countLeaders(crossed)
+	if r.Mode == ModeTimed && r.Round >= r.RoundLimit {
+		r.placeByDistance(StatusFinished) // time's up
+	}
+	if r.Mode == ModeElimination && r.Round%r.EliminationInterval == 0 {
+		r.eliminateLast()
+	}
+	if !r.Done() && r.Round >= r.MaxRounds {
+		r.CalledOff = true
+		r.End()
+	}
+
+	state := r.State()
+	for i := range state.Players {
+		state.Players[i].Run = runs[i]
+		state.Players[i].Event = events[i]
+	}
+	if r.recording {
+		r.Telemetry = append(r.Telemetry, state)
+	}
+	return state
+}
+
+// hasFinishLine reports whether the race is won by crossing the line, timed and
+// elimination races just use the track length for drawing
+func (r *Race) hasFinishLine() bool {
+	return r.Mode == ModeStandard || r.Mode == ModeRelay
+}
+
+// fillEnduranceDefaults gives players made without endurance attributes the defaults
+func fillEnduranceDefaults(player *Player) {
+	if player.Stamina <= 0 {
+		player.Stamina = DefaultStamina
+	}
+	if player.FatigueRate <= 0 {
+		player.FatigueRate = DefaultFatigueRate
+	}
+	if player.RecoveryRate <= 0 {
+		player.RecoveryRate = DefaultRecoveryRate(player.MinSpeed)
+	}
+}
+
+// handOver puts a relay team's runner for the given leg in the lane, the
+// fresh runner starts with full endurance
+func (p *Player) handOver(leg int) {
+	runner := p.Team[leg]
+	p.Leg = leg
+	p.MinSpeed = runner.MinSpeed
+	p.MaxSpeed = runner.MaxSpeed
+	p.Stamina = runner.Stamina
+	p.FatigueRate = runner.FatigueRate
+	p.RecoveryRate = runner.RecoveryRate
+	p.Affinities = runner.Affinities
+	p.EventChances = runner.EventChances
+	p.Strategy = runner.Strategy
+	p.Script = runner.Script
+	p.Napping = 0
+	fillEnduranceDefaults(p)
+	p.Endurance = p.Stamina
+	p.Resting = false
+}
+
+// LegEnd is how far along the track a relay leg finishes, the distance is split evenly between the team
+func (r *Race) LegEnd(i, leg int) float64 {
+	legs := len(r.Players[i].Team)
+	if legs == 0 {
+		return float64(r.TotalDistance)
+	}
+	return float64(r.TotalDistance) * float64(leg+1) / float64(legs)
+}
+
+// changeOver hands over to the next runner in lane i for every leg boundary
+// crossed this round, recording the split time each leg ended on
+func (r *Race) changeOver(i int, previousDistance, distanceRun float64) {
+	player := &r.Players[i]
+	for player.Leg < len(player.Team)-1 && player.Distance >= r.LegEnd(i, player.Leg) {
+		fraction := (r.LegEnd(i, player.Leg) - previousDistance) / distanceRun
+		player.Splits = append(player.Splits, float64(r.Round-1)+fraction)
+		player.handOver(player.Leg + 1)
+	}
+}
+
+// placeFinishers gives places to the players who crossed the line this round,
+// fastest crossing first, with players on the same time sharing a place
+func (r *Race) placeFinishers(crossed []int) {
+	sort.SliceStable(crossed, func(a, b int) bool {
+		return r.Players[crossed[a]].FinishTime < r.Players[crossed[b]].FinishTime
+	})
+	for k, i := range crossed {
+		if k > 0 && r.DeadHeat(crossed[k-1], i) {
+			r.Players[i].Place = r.Players[crossed[k-1]].Place
+		} else {
+			r.Players[i].Place = r.finishedPlayers + 1
+		}
+		r.Players[i].Status = StatusFinished
+		r.finishedPlayers++
+	}
+}
+
+// countLeaders gives a round led to whoever is furthest along, everyone level
+// at the front gets one. Once the winner is across the line the lead is
+// settled, so the rounds left aren't counted, and the round they cross in goes
+// to the first across rather than whoever overshot the line furthest.
+func (r *Race) countLeaders(crossed []int) {
+	if r.hasFinishLine() && r.finishedPlayers > len(crossed) {
+		return
+	}
+	if len(crossed) > 0 {
+		// placeFinishers has sorted them by crossing time
+		for _, i := range crossed {
+			if r.DeadHeat(crossed[0], i) {
+				r.Players[i].RoundsLed++
+			}
+		}
+		return
+	}
+
+	// knocked out animals in an elimination race don't count
+	furthest := 0.0
+	for _, player := range r.Players {
+		if !player.Finished {
+			furthest = math.Max(furthest, player.Distance)
+		}
+	}
+	if furthest == 0 {
+		return
+	}
+	for i := range r.Players {
+		if !r.Players[i].Finished && r.Players[i].Distance == furthest {
+			r.Players[i].RoundsLed++
+		}
+	}
+}
+
+// DeadHeat reports whether two finished players crossed the line at the same time
+func (r *Race) DeadHeat(i, j int) bool {
+	a, b := r.Players[i], r.Players[j]
+	return a.Finished && b.Finished && math.Abs(a.FinishTime-b.FinishTime) < deadHeatTolerance
+}
+
+// Run steps the race until everyone has finished or it is called off
+func (r *Race) Run() {
+	for !r.Done() {
+		r.Step()
+	}
+}
+
+// State takes a snapshot of the race as it is right now
+func (r *Race) State() RoundState {
+	state := RoundState{Round: r.Round, Players: make([]PlayerState, len(r.Players))}
+	for i, player := range r.Players {
+		state.Players[i] = PlayerState{
+			Distance:   player.Distance,
+			Endurance:  player.Endurance,
+			Resting:    player.Resting,
+			Finished:   player.Finished,
+			Place:      player.Place,
+			FinishTime: player.FinishTime,
+			Eliminated: player.EliminatedRound > 0,
+			Leg:        player.Leg,
+		}
+	}
+	return state
+}
+
+// End stops the race where it is, anyone still running is marked DNF and
+// placed behind the finishers by how far they got. In an elimination race the
+// knocked out animals already hold the last places so DNFs go ahead of them.
+func (r *Race) End() {
+	r.placeByDistance(StatusDNF)
+}
+
+// eliminateLast knocks out whoever is furthest behind, everyone level in last
+// place goes out together sharing the place (so a field that is all level
+// shares the win). Knocked out animals take the last places left, so the
+// first out is last, and the one left standing wins.
+func (r *Race) eliminateLast() {
+	var running []int
+	for i, player := range r.Players {
+		if !player.Finished {
+			running = append(running, i)
+		}
+	}
+	if len(running) == 0 {
+		return
+	}
+
+	lowest := math.Inf(1)
+	for _, i := range running {
+		lowest = math.Min(lowest, r.Players[i].Distance)
+	}
+	var last []int
+	for _, i := range running {
+		if r.Players[i].Distance == lowest {
+			last = append(last, i)
+		}
+	}
+	if len(last) == len(running) {
+		// knocking out everyone left would leave no winner, so a lone animal
+		// wins and a level field shares the win
+		r.placeByDistance(StatusFinished)
+		return
+	}
+	place := len(running) - len(last) + 1
+	for _, i := range last {
+		r.Players[i].Place = place
+		r.Players[i].Finished = true
+		r.Players[i].Status = StatusFinished
+		r.Players[i].EliminatedRound = r.Round
+		r.finishedPlayers++
+	}
+
+	// the last one standing wins
+	if len(running)-len(last) == 1 {
+		r.placeByDistance(StatusFinished)
+	}
+}
+
+// placeByDistance places everyone still running behind the finishers, furthest
+// first, with players on the same distance sharing a place
+func (r *Race) placeByDistance(status string) {
+	var running []int
+	for i, player := range r.Players {
+		if !player.Finished && player.Status != StatusDNF {
+			running = append(running, i)
+		}
+	}
+	sort.SliceStable(running, func(a, b int) bool {
+		return r.Players[running[a]].Distance > r.Players[running[b]].Distance
+	})
+
+	placed := len(r.Players) - len(running)
+	if r.Mode == ModeElimination {
+		placed = 0 // the knocked out animals are behind, not ahead
+	}
+	for k, i := range running {
+		if k > 0 && r.Players[running[k-1]].Distance == r.Players[i].Distance {
+			r.Players[i].Place = r.Players[running[k-1]].Place
+		} else {
+			r.Players[i].Place = placed + k + 1
+		}
+		r.Players[i].Status = status
+		r.Players[i].Finished = status == StatusFinished
+	}
+	r.finishedPlayers = len(r.Players)
+}
+
+// maxRounds is how many rounds a race gets before it is called off, a timed
+// or elimination race always gets the rounds its mode needs
+func maxRounds(players []Player, totalDistance int, config RaceConfig) int {
+	limit := config.MaxRounds
+	if limit <= 0 {
+		slowest := math.Inf(1)
+		for _, player := range players {
+			slowest = math.Min(slowest, player.MinSpeed)
+			for _, runner := range player.Team {
+				slowest = math.Min(slowest, runner.MinSpeed)
+			}
+		}
+		rounds := float64(maxRoundLimit)
+		if slowest > 0 && !math.IsInf(slowest, 1) {
+			rounds = math.Min(rounds, roundLimitFactor*float64(totalDistance)/slowest)
+		}
+		limit = int(math.Max(minRoundLimit, rounds))
+	}
+	switch config.Mode {
+	case ModeTimed:
+		limit = max(limit, config.RoundLimit)
+	case ModeElimination:
+		limit = max(limit, config.EliminationInterval*len(players))
+	}
+	return limit
+}
+
+// timedTrackLength is how long to draw the track for a timed race, far enough
+// that the fastest animal running flat out every round would just reach the end
+func timedTrackLength(players []Player, rounds int) int {
+	fastest := 0.0
+	for _, player := range players {
+		fastest = math.Max(fastest, player.MaxSpeed)
+	}
+	return int(math.Max(1, math.Ceil(fastest*float64(rounds))))
+}
@@ -0,0 +1,289 @@
+package simulation
+
+import (
+	"math"
+	"os"
+	"reflect"
+	"testing"
+)
+
+// field is a few animals whose races come down to the random source
+func field() []Player {
+	return []Player{
+		{Name: "Hare", UUID: "hare", MinSpeed: 2, MaxSpeed: 12},
+		{Name: "Tortoise", UUID: "tortoise", MinSpeed: 4, MaxSpeed: 6},
+		{Name: "Fox", UUID: "fox", MinSpeed: 3, MaxSpeed: 9},
+	}
+}
+
+// steady is an animal that runs the same speed every round
+func steady(name string, speed float64) Player {
+	return Player{Name: name, UUID: name, MinSpeed: speed, MaxSpeed: speed}
+}
+
+// scriptedModel runs the named animals the distances listed for them, one a
+// round with the last repeated, so races with it play out the same whatever
+// the seed. Anyone not listed runs as in the classic model.
+type scriptedModel struct {
+	classicModel
+	runs map[string][]float64
+}
+
+func (m scriptedModel) Run(r *Race, i int) float64 {
+	runs, ok := m.runs[r.Players[i].Name]
+	if !ok {
+		return m.classicModel.Run(r, i)
+	}
+	return runs[min(r.Round, len(runs))-1]
+}
+
+// playerNamed finds an animal in the race by name
+func playerNamed(t *testing.T, race *Race, name string) Player {
+	t.Helper()
+	for _, player := range race.Players {
+		if player.Name == name {
+			return player
+		}
+	}
+	t.Fatalf("%s isn't in the race", name)
+	return Player{}
+}
+
+// inDataDir runs the rest of the test from an empty directory with a data
+// folder, as races are saved to and loaded from data/
+func inDataDir(t *testing.T) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+	if err := os.Mkdir("data", 0755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSameSeedSameRace(t *testing.T) {
+	run := func(seed int64) *Race {
+		race := NewRace(field(), RaceConfig{TotalDistance: 200, Seed: seed, Telemetry: true})
+		race.Run()
+		return race
+	}
+	// the telemetry has every round, so this covers how the race went as well as how it ended
+	first, second := run(42), run(42)
+	if len(first.Telemetry) == 0 || !reflect.DeepEqual(first.Telemetry, second.Telemetry) {
+		t.Errorf("seed 42 ran %+v then %+v", first.Telemetry, second.Telemetry)
+	}
+	if other := run(7); reflect.DeepEqual(other.Telemetry, first.Telemetry) {
+		t.Error("seeds 7 and 42 ran the same race")
+	}
+}
+
+func TestSavedRaceReruns(t *testing.T) {
+	inDataDir(t)
+	race := NewRace(field(), RaceConfig{TotalDistance: 200, Seed: 42, Telemetry: true})
+	race.Run()
+	SaveRaceResults(race, "saved")
+
+	players, config, err := LoadRaceSetup("saved")
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.Telemetry = true
+	rerun := NewRace(players, config)
+	rerun.Run()
+	if !reflect.DeepEqual(rerun.Telemetry, race.Telemetry) {
+		t.Errorf("re-run went %+v, the race went %+v", rerun.Telemetry, race.Telemetry)
+	}
+}
+
+func TestPlacesByCrossingTime(t *testing.T) {
+	// the hare is furthest past the line after the second round, but the
+	// tortoise got across a third of the way through the round
+	race := NewRace([]Player{steady("Hare", 1), steady("Tortoise", 1), steady("Fox", 3)}, RaceConfig{TotalDistance: 10})
+	race.Model = scriptedModel{runs: map[string][]float64{"Hare": {1, 20}, "Tortoise": {9.5, 1.5}}}
+	race.Run()
+
+	tests := []struct {
+		name       string
+		place      int
+		finishTime float64
+	}{
+		{"Tortoise", 1, 1 + 1.0/3},
+		{"Hare", 2, 1.45},
+		{"Fox", 3, 3 + 1.0/3},
+	}
+	for _, test := range tests {
+		player := playerNamed(t, race, test.name)
+		if player.Place != test.place || math.Abs(player.FinishTime-test.finishTime) > 1e-9 {
+			t.Errorf("%s: place %d in %v rounds, want place %d in %v", test.name, player.Place, player.FinishTime, test.place, test.finishTime)
+		}
+	}
+}
+
+func TestDeadHeatSharesPlace(t *testing.T) {
+	// the hare and tortoise both cross the line halfway through the third round
+	race := NewRace([]Player{steady("Hare", 1), steady("Tortoise", 4), steady("Fox", 3)}, RaceConfig{TotalDistance: 10})
+	race.Model = scriptedModel{runs: map[string][]float64{"Hare": {5, 0, 10}}}
+	race.Run()
+
+	hare, tortoise, fox := playerNamed(t, race, "Hare"), playerNamed(t, race, "Tortoise"), playerNamed(t, race, "Fox")
+	if hare.Place != 1 || tortoise.Place != 1 || !race.DeadHeat(0, 1) {
+		t.Errorf("places %d and %d with DeadHeat %v, want a dead heat for 1st", hare.Place, tortoise.Place, race.DeadHeat(0, 1))
+	}
+	if fox.Place != 3 {
+		t.Errorf("Fox place %d, want 3rd after the dead heat", fox.Place)
+	}
+}
+
+func TestEndedRacePlacesDNFsByDistance(t *testing.T) {
+	race := NewRace([]Player{steady("Tortoise", 1), steady("Hare", 3), steady("Fox", 2)}, RaceConfig{TotalDistance: 10})
+	for !race.Done() && !playerNamed(t, race, "Hare").Finished {
+		race.Step()
+	}
+	race.End()
+
+	tests := []struct {
+		name   string
+		place  int
+		status string
+	}{
+		{"Hare", 1, StatusFinished},
+		{"Fox", 2, StatusDNF},
+		{"Tortoise", 3, StatusDNF},
+	}
+	for _, test := range tests {
+		player := playerNamed(t, race, test.name)
+		if player.Place != test.place || player.Status != test.status {
+			t.Errorf("%s: place %d %s, want place %d %s", test.name, player.Place, player.Status, test.place, test.status)
+		}
+	}
+	if !race.Done() {
+		t.Error("race isn't done after End")
+	}
+}
+
+func TestTimedRacePlacesByDistance(t *testing.T) {
+	tests := []struct {
+		policy string
+		points map[string]float64
+	}{
+		// 4, 3, 2 and 1 points for 1st to 4th, the tortoise and snail tie for 3rd
+		{TiePolicyShared, map[string]float64{"Hare": 4, "Fox": 3, "Tortoise": 2, "Snail": 2}},
+		{TiePolicySplit, map[string]float64{"Hare": 4, "Fox": 3, "Tortoise": 1.5, "Snail": 1.5}},
+	}
+	for _, test := range tests {
+		t.Run(test.policy, func(t *testing.T) {
+			players := []Player{steady("Tortoise", 1), steady("Hare", 3), steady("Snail", 1), steady("Fox", 2)}
+			race := NewRace(players, RaceConfig{Mode: ModeTimed, RoundLimit: 5, TiePolicy: test.policy})
+			race.Run()
+			CalculateScores(race)
+
+			if race.Round != 5 {
+				t.Errorf("timed race ran %d rounds, want 5", race.Round)
+			}
+			places := map[string]int{"Hare": 1, "Fox": 2, "Tortoise": 3, "Snail": 3}
+			for name, place := range places {
+				player := playerNamed(t, race, name)
+				if player.Place != place || player.Status != StatusFinished {
+					t.Errorf("%s: place %d %s after %v metres, want place %d", name, player.Place, player.Status, player.Distance, place)
+				}
+				if player.Score != test.points[name] {
+					t.Errorf("%s scored %v, want %v", name, player.Score, test.points[name])
+				}
+			}
+		})
+	}
+}
+
+func TestEliminationPlacing(t *testing.T) {
+	tests := []struct {
+		name    string
+		players []Player
+		places  map[string]int
+		rounds  map[string]int // round each animal was knocked out, 0 for the winner
+	}{
+		{
+			name:    "one out each round",
+			players: []Player{steady("Tortoise", 1), steady("Hare", 3), steady("Fox", 2)},
+			places:  map[string]int{"Hare": 1, "Fox": 2, "Tortoise": 3},
+			rounds:  map[string]int{"Hare": 0, "Fox": 2, "Tortoise": 1},
+		},
+		{
+			name:    "level last go out together",
+			players: []Player{steady("Tortoise", 1), steady("Hare", 3), steady("Snail", 1)},
+			places:  map[string]int{"Hare": 1, "Tortoise": 2, "Snail": 2},
+			rounds:  map[string]int{"Hare": 0, "Tortoise": 1, "Snail": 1},
+		},
+		{
+			name:    "one animal wins",
+			players: []Player{steady("Hare", 3)},
+			places:  map[string]int{"Hare": 1},
+			rounds:  map[string]int{"Hare": 0},
+		},
+		{
+			name:    "level field shares the win",
+			players: []Player{steady("Tortoise", 1), steady("Snail", 1)},
+			places:  map[string]int{"Tortoise": 1, "Snail": 1},
+			rounds:  map[string]int{"Tortoise": 0, "Snail": 0},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			race := NewRace(test.players, RaceConfig{Mode: ModeElimination, EliminationInterval: 1})
+			race.Run()
+			for name, place := range test.places {
+				player := playerNamed(t, race, name)
+				if player.Place != place || player.EliminatedRound != test.rounds[name] || player.Status != StatusFinished {
+					t.Errorf("%s: place %d knocked out in round %d (%s), want place %d in round %d",
+						name, player.Place, player.EliminatedRound, player.Status, place, test.rounds[name])
+				}
+			}
+		})
+	}
+}
+
+func TestRelayHandover(t *testing.T) {
+	teams := []Team{
+		{Name: "Fable", UUID: "fable", Members: []string{"hare", "tortoise"}},
+		{Name: "Woods", UUID: "woods", Members: []string{"fox", "tortoise", "hare"}},
+	}
+	// the hare has the stamina to build up to a sprint without resting, the
+	// tortoise taking over from it should start again from its own min speed
+	roster := field()
+	roster[0].Stamina = 1000
+	tortoise := roster[1]
+	players, err := TeamPlayers(teams, roster)
+	if err != nil {
+		t.Fatal(err)
+	}
+	race := NewRace(players, RaceConfig{Mode: ModeRelay, TotalDistance: 300, Seed: 1, Model: "Acceleration"})
+	model := race.Model.(*accelerationModel)
+
+	firstRun := false
+	for !race.Done() {
+		leg := race.Players[0].Leg
+		race.Step()
+		if firstRun && model.speeds[0] > tortoise.MinSpeed+(tortoise.MaxSpeed-tortoise.MinSpeed)/3 {
+			t.Errorf("tortoise's first round after the handover ran at %v", model.speeds[0])
+		}
+		firstRun = leg == 0 && race.Players[0].Leg == 1
+	}
+
+	for _, player := range race.Players {
+		if len(player.Splits) != len(player.Team) {
+			t.Fatalf("%s has splits %v for %d legs", player.Name, player.Splits, len(player.Team))
+		}
+		for k := 1; k < len(player.Splits); k++ {
+			if player.Splits[k] <= player.Splits[k-1] {
+				t.Errorf("%s's splits %v don't go up", player.Name, player.Splits)
+			}
+		}
+		if last := player.Splits[len(player.Splits)-1]; last != player.FinishTime {
+			t.Errorf("%s's last split is %v, want its finish time %v", player.Name, last, player.FinishTime)
+		}
+	}
+}
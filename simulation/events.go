@@ -0,0 +1,160 @@
+package simulation
+//import some stuff
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// random things that can happen to an animal during a race
+const (
+	EventNap         = "Nap"         // a leader well clear of the field lies down for a few rounds
+	EventStumble     = "Stumble"     // only gets part of the way this round
+	EventBurst       = "Burst"       // a burst of speed this round, which tires it out faster
+	EventDistraction = "Distraction" // stops to look at something and loses the round
+)
+
+// EventTypes lists the events in the order they are rolled for
+var EventTypes = []string{EventNap, EventStumble, EventBurst, EventDistraction}
+
+// DefaultEventChances are the chances each round of each event for animals
+// without their own, a nap is only ever rolled for a comfortable leader
+var DefaultEventChances = map[string]float64{
+	EventNap:         0.2,
+	EventStumble:     0.03,
+	EventBurst:       0.05,
+	EventDistraction: 0.03,
+}
+
+// how the events play out
+const (
+	napLead      = 0.1 // share of the race a leader has to be clear of the field by to nap
+	napRounds    = 3   // rounds a nap lasts
+	stumbleSpeed = 0.3 // share of the run a stumbling animal manages
+	burstSpeed   = 1.6 // how much further a burst takes an animal
+)
+
+// RaceEvent is an event that happened in a race, kept in the race record
+type RaceEvent struct {
+	Round int
+	Lane  int
+	Event string
+}
+
+// EventChance is the chance each round of the event happening to the animal
+func (p Player) EventChance(event string) float64 {
+	if chance, ok := p.EventChances[event]; ok {
+		return chance
+	}
+	return DefaultEventChances[event]
+}
+
+// rollEvent picks what, if anything, happens to player i this round. Nothing
+// is rolled unless the race has events turned on, so races without them use
+// the seed exactly as they did before events were added.
+func (r *Race) rollEvent(i int) string {
+	if !r.RandomEvents {
+		return ""
+	}
+	player := r.Players[i]
+	for _, event := range EventTypes {
+		roll := r.rng.Float64()
+		if event == EventNap && r.leadOverField(i) <= napLead*float64(r.TotalDistance) {
+			continue // only a leader gets complacent enough to nap
+		}
+		if roll < player.EventChance(event) {
+			return event
+		}
+	}
+	return ""
+}
+
+// eventSpeed is how an event changes how far the animal gets this round
+func eventSpeed(event string) float64 {
+	switch event {
+	case EventStumble:
+		return stumbleSpeed
+	case EventBurst:
+		return burstSpeed
+	}
+	return 1
+}
+
+// logEvent records an event in the race record
+func (r *Race) logEvent(i int, event string) {
+	r.Events = append(r.Events, RaceEvent{Round: r.Round, Lane: i, Event: event})
+}
+
+// playerEvents lists the events that happened in a lane as round:event separated by ;
+func playerEvents(race *Race, lane int) string {
+	var parts []string
+	for _, event := range race.Events {
+		if event.Lane == lane {
+			parts = append(parts, fmt.Sprintf("%d:%s", event.Round, event.Event))
+		}
+	}
+	return strings.Join(parts, ";")
+}
+
+// EventCaption is the text shown over an animal's lane for an event
+func EventCaption(event string) string {
+	switch event {
+	case EventNap:
+		return "Zzz... napping"
+	case EventStumble:
+		return "Stumbled!"
+	case EventBurst:
+		return "Burst of speed!"
+	case EventDistraction:
+		return "Distracted!"
+	}
+	return ""
+}
+
+// FormatEventChances writes an animal's event chances as event:chance
+// separated by ;, in the order of EventTypes
+func FormatEventChances(chances map[string]float64) string {
+	var parts []string
+	for _, event := range EventTypes {
+		if chance, ok := chances[event]; ok {
+			parts = append(parts, event+":"+strconv.FormatFloat(chance, 'f', -1, 64))
+		}
+	}
+	return strings.Join(parts, ";")
+}
+
+// ParseEventChances reads chances written by FormatEventChances, blank means
+// the animal has the default chances
+func ParseEventChances(text string) (map[string]float64, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, nil
+	}
+	chances := make(map[string]float64)
+	for _, part := range strings.Split(text, ";") {
+		fields := strings.Split(strings.TrimSpace(part), ":")
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("invalid event chance %q, use event:chance like Nap:0.5", part)
+		}
+		event := strings.TrimSpace(fields[0])
+		if _, ok := DefaultEventChances[event]; !ok {
+			return nil, fmt.Errorf("unknown event %q in chance %q", event, part)
+		}
+		chance, err := strconv.ParseFloat(strings.TrimSpace(fields[1]), 64)
+		if err != nil || chance < 0 || chance > 1 {
+			return nil, fmt.Errorf("invalid event chance %q, the chance must be between 0 and 1", part)
+		}
+		chances[event] = chance
+	}
+	return chances, nil
+}
+
+// recordEventChances reads the event chances column of an animal record,
+// older records and anything that won't parse get the defaults
+func recordEventChances(record []string) map[string]float64 {
+	if len(record) <= 9 {
+		return nil
+	}
+	chances, _ := ParseEventChances(record[9])
+	return chances
+}
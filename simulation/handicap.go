@@ -0,0 +1,126 @@
+package simulation
+//import some stuff
+import (
+	"fmt"
+	"math"
+)
+
+// handicap methods, the head starts are worked out before the race and handed to it in the config
+const (
+	HandicapNone    = "None"
+	HandicapSpeed   = "Speed"   // from how fast the animal runs the distance on its own
+	HandicapHistory = "History" // from how fast the animal ran its saved races
+)
+
+// HandicapMethods lists the methods for the race setup menu
+var HandicapMethods = []string{HandicapNone, HandicapSpeed, HandicapHistory}
+
+// handicapRuns is how many solo races soloRounds averages over
+const handicapRuns = 200
+
+// maxHeadStart is the biggest head start as a share of the race, so a very
+// slow animal doesn't start most of the way to the line
+const maxHeadStart = 0.9
+
+// soloRounds is how many rounds, on average, the player takes to run the last
+// distance metres of the race on its own, so it covers the same stretch of
+// track as it would from a head start. The same seeds are used for every
+// distance so longer distances always take longer. An animal that can't finish
+// has no time to handicap it by.
+func soloRounds(player Player, distance int, config RaceConfig) (float64, error) {
+	// the race as it was set up, track, weather and all, timed over the distance
+	solo := config
+	solo.Mode = ModeStandard
+	solo.Telemetry = false
+	solo.Handicaps = map[string]float64{player.UUID: float64(config.TotalDistance - distance)}
+	totalRounds := 0.0
+	for k := 0; k < handicapRuns; k++ {
+		solo.Seed = config.Seed + int64(k)
+		race := NewRace([]Player{player}, solo)
+		race.Run()
+		if race.CalledOff {
+			return 0, fmt.Errorf("%s can't finish %dm on its own, check its endurance attributes", player.Name, distance)
+		}
+		totalRounds += race.Players[0].FinishTime
+	}
+	return totalRounds / handicapRuns, nil
+}
+
+// EstimatePaces works out how many metres a round each player averages over
+// the race distance, resting included, by running each of them on their own
+func EstimatePaces(players []Player, config RaceConfig) ([]float64, error) {
+	paces := make([]float64, len(players))
+	for i, player := range players {
+		rounds, err := soloRounds(player, config.TotalDistance, config)
+		if err != nil {
+			return nil, err
+		}
+		if rounds > 0 {
+			paces[i] = float64(config.TotalDistance) / rounds
+		}
+	}
+	return paces, nil
+}
+
+// Handicaps works out head starts so every player is expected to reach the
+// line at the same time as the fastest, who starts on the line. Expected times
+// come from running each player on their own, multiplied by the player's
+// scale (nil means 1 for everyone) so a history based handicap can make an
+// animal slower or faster than its speeds suggest. The head start is found by
+// searching for the distance the player covers in the fastest player's time,
+// as a shorter run needs fewer rests. The result is keyed by UUID ready for
+// RaceConfig.Handicaps.
+func Handicaps(players []Player, config RaceConfig, scale []float64) (map[string]float64, error) {
+	handicaps := make(map[string]float64)
+	if len(players) == 0 || config.TotalDistance <= 0 {
+		return handicaps, nil
+	}
+	expected := func(i, distance int) (float64, error) {
+		rounds, err := soloRounds(players[i], distance, config)
+		if scale != nil && scale[i] > 0 {
+			rounds *= scale[i]
+		}
+		return rounds, err
+	}
+
+	times := make([]float64, len(players))
+	target := math.Inf(1)
+	for i := range players {
+		var err error
+		if times[i], err = expected(i, config.TotalDistance); err != nil {
+			return nil, err
+		}
+		target = math.Min(target, times[i])
+	}
+
+	shortest := int(math.Ceil((1 - maxHeadStart) * float64(config.TotalDistance)))
+	for i, player := range players {
+		// find the longest distance the player covers within the target time
+		low, high := shortest, config.TotalDistance
+		if times[i] <= target {
+			low = high
+		}
+		for low < high {
+			middle := (low + high + 1) / 2
+			rounds, err := expected(i, middle)
+			if err != nil {
+				return nil, err
+			}
+			if rounds <= target {
+				low = middle
+			} else {
+				high = middle - 1
+			}
+		}
+		handicaps[player.UUID] = float64(config.TotalDistance - low)
+	}
+	return handicaps, nil
+}
+
+// FormatHandicap writes a head start for the results, blank if there wasn't one
+func FormatHandicap(handicap float64) string {
+	if handicap == 0 {
+		return ""
+	}
+	return fmt.Sprintf("+%.1f head start", handicap)
+}
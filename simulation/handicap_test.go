@@ -0,0 +1,41 @@
+package simulation
+
+import (
+	"math"
+	"testing"
+)
+
+func TestHandicapsEvenOutTerrain(t *testing.T) {
+	// the water is near the end, so an animal with a head start still has to swim it
+	var segments []Segment
+	for _, terrain := range []string{TerrainUphill, TerrainFlat, TerrainWater} {
+		segment, err := NewSegment(terrain, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		segments = append(segments, segment)
+	}
+	config := RaceConfig{TotalDistance: 300, Seed: 1, Track: Track{Name: "Hills", Segments: segments}, Weather: WeatherRain}
+	handicaps, err := Handicaps(field(), config, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// everyone should take about as long to run from their head start on fresh seeds
+	config.Handicaps = handicaps
+	fastest, slowest := math.Inf(1), 0.0
+	for _, player := range field() {
+		rounds := 0.0
+		for k := 0; k < handicapRuns; k++ {
+			config.Seed = 1000 + int64(k)
+			race := NewRace([]Player{player}, config)
+			race.Run()
+			rounds += race.Players[0].FinishTime
+		}
+		rounds /= handicapRuns
+		fastest, slowest = math.Min(fastest, rounds), math.Max(slowest, rounds)
+	}
+	if slowest > fastest*1.05 {
+		t.Errorf("handicapped solo times run from %.2f to %.2f rounds, want them within 5%%", fastest, slowest)
+	}
+}
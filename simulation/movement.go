@@ -0,0 +1,157 @@
+package simulation
+//import some stuff
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// DefaultMovementModel is the model races use when none is picked
+const DefaultMovementModel = "Classic"
+
+// MovementModel decides how far a player runs each round and what it costs them
+type MovementModel interface {
+	Name() string
+	// Run is how far player i tries to run this round
+	Run(r *Race, i int) float64
+	// Drain is how much endurance running that far costs
+	Drain(r *Race, i int, run float64) float64
+	// Recover is how much endurance a resting player gets back
+	Recover(r *Race, i int) float64
+}
+
+// movementModels builds a fresh model per race as some models keep state
+var movementModels = map[string]func(players int) MovementModel{
+	"Classic":      func(players int) MovementModel { return classicModel{} },
+	"Normal":       func(players int) MovementModel { return normalModel{} },
+	"Acceleration": func(players int) MovementModel { return newAccelerationModel(players) },
+	"Fable":        func(players int) MovementModel { return fableModel{} },
+}
+
+// MovementModelNames lists the models for the race setup menu
+func MovementModelNames() []string {
+	names := make([]string, 0, len(movementModels))
+	for name := range movementModels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewMovementModel creates the named model for a race with the given number of players
+func NewMovementModel(name string, players int) (MovementModel, error) {
+	if name == "" {
+		name = DefaultMovementModel
+	}
+	create, ok := movementModels[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown movement model %q", name)
+	}
+	return create(players), nil
+}
+
+// classicModel is the original rule: a uniform speed between min and max,
+// endurance drains by the distance run times the animal's fatigue rate and
+// resting gives back the animal's recovery rate
+type classicModel struct{}
+
+func (classicModel) Name() string { return "Classic" }
+
+func (classicModel) Run(r *Race, i int) float64 {
+	return RandomFloat(r.rng, r.Players[i].MinSpeed, r.Players[i].MaxSpeed)
+}
+
+func (classicModel) Drain(r *Race, i int, run float64) float64 {
+	return run * r.Players[i].FatigueRate
+}
+
+func (classicModel) Recover(r *Race, i int) float64 {
+	return r.Players[i].RecoveryRate
+}
+
+// normalModel runs close to the middle of the speed range most rounds,
+// with the range covering about two standard deviations either side
+type normalModel struct {
+	classicModel
+}
+
+func (normalModel) Name() string { return "Normal" }
+
+func (normalModel) Run(r *Race, i int) float64 {
+	player := r.Players[i]
+	mean := (player.MinSpeed + player.MaxSpeed) / 2
+	deviation := (player.MaxSpeed - player.MinSpeed) / 4
+	speed := mean + r.rng.NormFloat64()*deviation
+	return math.Max(player.MinSpeed, math.Min(player.MaxSpeed, speed))
+}
+
+// accelerationModel starts everyone at min speed and builds up towards max
+// speed, a rest means building up again from min speed
+type accelerationModel struct {
+	classicModel
+	speeds []float64
+	legs   []int // relay leg each speed was built up on
+}
+
+func newAccelerationModel(players int) *accelerationModel {
+	return &accelerationModel{speeds: make([]float64, players), legs: make([]int, players)}
+}
+
+func (*accelerationModel) Name() string { return "Acceleration" }
+
+func (m *accelerationModel) Run(r *Race, i int) float64 {
+	player := r.Players[i]
+	if player.Leg != m.legs[i] {
+		// a fresh runner takes over, they start from their own min speed
+		m.legs[i] = player.Leg
+		m.speeds[i] = 0
+	}
+	if m.speeds[i] < player.MinSpeed {
+		m.speeds[i] = player.MinSpeed
+	}
+	// speed up by up to a third of the speed range each round
+	m.speeds[i] += RandomFloat(r.rng, 0, (player.MaxSpeed-player.MinSpeed)/3)
+	if m.speeds[i] > player.MaxSpeed {
+		m.speeds[i] = player.MaxSpeed
+	}
+	return m.speeds[i]
+}
+
+func (m *accelerationModel) Recover(r *Race, i int) float64 {
+	m.speeds[i] = r.Players[i].MinSpeed
+	return m.classicModel.Recover(r, i)
+}
+
+// fableModel is the hare and the tortoise: a leader who is well clear of the
+// field gets complacent and half the time only ambles along
+type fableModel struct {
+	classicModel
+}
+
+// fableLead is how far ahead, as a share of the race, a leader has to be to get complacent
+const fableLead = 0.1
+
+func (fableModel) Name() string { return "Fable" }
+
+func (m fableModel) Run(r *Race, i int) float64 {
+	player := r.Players[i]
+	if r.leadOverField(i) > fableLead*float64(r.TotalDistance) && r.rng.Float64() < 0.5 {
+		return RandomFloat(r.rng, 0, player.MinSpeed)
+	}
+	return m.classicModel.Run(r, i)
+}
+
+// leadOverField is how far player i is ahead of the next unfinished player, 0 if they aren't leading
+func (r *Race) leadOverField(i int) float64 {
+	lead := math.Inf(1)
+	for j, other := range r.Players {
+		if j == i || other.Finished {
+			continue
+		}
+		lead = math.Min(lead, r.Players[i].Distance-other.Distance)
+	}
+	if math.IsInf(lead, 1) || lead < 0 {
+		return 0
+	}
+	return lead
+}
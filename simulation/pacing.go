@@ -0,0 +1,94 @@
+package simulation
+//import some stuff
+import (
+	"fmt"
+)
+
+// pacing strategies, how hard an animal pushes through the race
+const (
+	StrategyFlatOut      = "Flat Out"      // full effort until it has to rest, how every race used to be run
+	StrategyEven         = "Even Pace"     // a steady effort the whole way
+	StrategySprintFinish = "Sprint Finish" // holds back then goes all out for the last stretch
+	StrategyFrontRunner  = "Front Runner"  // goes all out early then hangs on
+	StrategyConserve     = "Conserve"      // eases off whenever endurance runs low instead of running until it drops
+)
+
+// Strategies lists the pacing strategies for the menus
+var Strategies = []string{StrategyFlatOut, StrategyEven, StrategySprintFinish, StrategyFrontRunner, StrategyConserve}
+
+// how the strategies pace themselves
+const (
+	evenEffort        = 0.75 // share of the speed range an even pace uses
+	easyEffort        = 0.6  // how hard a sprint finisher or front runner goes when not pushing
+	sprintFrom        = 0.75 // share of the race after which a sprint finisher goes all out
+	frontRunnerUntil  = 0.5  // share of the race a front runner goes all out for
+	conserveThreshold = 0.4  // share of stamina below which a conserving animal eases off
+	conserveEffort    = 0.4
+)
+
+// ValidateStrategy checks a strategy name, blank is allowed and means StrategyFlatOut
+func ValidateStrategy(strategy string) error {
+	if strategy == "" {
+		return nil
+	}
+	for _, known := range Strategies {
+		if strategy == known {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown pacing strategy %q", strategy)
+}
+
+// StrategyName is the strategy's name, StrategyFlatOut for an animal without one
+func StrategyName(strategy string) string {
+	if strategy == "" {
+		return StrategyFlatOut
+	}
+	return strategy
+}
+
+// effort is how much of player i's speed range their strategy uses this
+// round, from 0 for min speed to 1 for whatever the movement model gave
+func (r *Race) effort(i int) float64 {
+	player := r.Players[i]
+	progress := player.Distance / float64(r.TotalDistance)
+	switch player.Strategy {
+	case StrategyEven:
+		return evenEffort
+	case StrategySprintFinish:
+		if progress >= sprintFrom {
+			return 1
+		}
+		return easyEffort
+	case StrategyFrontRunner:
+		if progress < frontRunnerUntil {
+			return 1
+		}
+		return easyEffort
+	case StrategyConserve:
+		if player.Endurance < conserveThreshold*player.Stamina {
+			return conserveEffort
+		}
+		return 1
+	}
+	return 1
+}
+
+// pace holds player i's run back to the effort they decided on, an animal
+// never goes slower than its min speed by choice
+func (r *Race) pace(i int, effort, run float64) float64 {
+	minSpeed := r.Players[i].MinSpeed
+	if run <= minSpeed {
+		return run
+	}
+	return minSpeed + effort*(run-minSpeed)
+}
+
+// recordStrategy reads the strategy column of an animal record, older
+// records and unknown strategies run flat out
+func recordStrategy(record []string) string {
+	if len(record) <= 10 || ValidateStrategy(record[10]) != nil {
+		return ""
+	}
+	return record[10]
+}
@@ -0,0 +1,121 @@
+package simulation
+//import some stuff
+import (
+	"runtime"
+	"sync"
+)
+
+// DefaultPredictionRuns is how many races the estimator runs if it isn't told otherwise
+const DefaultPredictionRuns = 5000
+
+// Prediction is what the estimator worked out for one animal
+type Prediction struct {
+	Name              string
+	UUID              string
+	WinProbability    float64
+	ExpectedPlace     float64
+	PlaceDistribution []float64 // chance of each place, index 0 is 1st
+	DNFRate           float64   // chance of not finishing, those races are still placed by distance
+}
+
+// PredictionResult is the outcome of a Monte Carlo run for a whole field
+type PredictionResult struct {
+	Runs           int
+	CalledOff      int // races that hit their round limit, their DNFs are placed by how far they got
+	ExpectedRounds float64
+	Players        []Prediction // in the same order as the players passed in
+}
+
+// predictionTally is the running totals each worker keeps
+type predictionTally struct {
+	places    [][]int // places[player][place-1] is how often that player got that place
+	dnfs      []int   // dnfs[player] is how often that player didn't finish
+	rounds    int
+	calledOff int
+}
+
+// EstimateWinProbabilities runs the race many times across every CPU core and
+// reports how likely each animal is to win. Run k uses seed config.Seed+k so
+// the same seed always gives the same estimate.
+func EstimateWinProbabilities(players []Player, config RaceConfig, runs int) PredictionResult {
+	if runs <= 0 {
+		runs = DefaultPredictionRuns
+	}
+	config.Telemetry = false // thousands of races, don't keep every round
+
+	workers := runtime.NumCPU()
+	if workers > runs {
+		workers = runs
+	}
+	tallies := make([]predictionTally, workers)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			tally := predictionTally{places: make([][]int, len(players)), dnfs: make([]int, len(players))}
+			for i := range tally.places {
+				tally.places[i] = make([]int, len(players))
+			}
+
+			field := make([]Player, len(players))
+			for k := w; k < runs; k += workers {
+				copy(field, players) // NewRace resets the players it is given
+				runConfig := config
+				runConfig.Seed = config.Seed + int64(k)
+				race := NewRace(field, runConfig)
+				race.Run()
+
+				tally.rounds += race.Round
+				if race.CalledOff {
+					tally.calledOff++
+				}
+				for i, player := range race.Players {
+					if player.Place > 0 && player.Place <= len(players) {
+						tally.places[i][player.Place-1]++
+					}
+					if player.Status == StatusDNF {
+						tally.dnfs[i]++
+					}
+				}
+			}
+			tallies[w] = tally
+		}(w)
+	}
+	wg.Wait()
+
+	// merge the workers' totals
+	result := PredictionResult{Runs: runs, Players: make([]Prediction, len(players))}
+	totalRounds := 0
+	for _, tally := range tallies {
+		totalRounds += tally.rounds
+		result.CalledOff += tally.calledOff
+	}
+	result.ExpectedRounds = float64(totalRounds) / float64(runs)
+
+	for i, player := range players {
+		prediction := Prediction{
+			Name:              player.Name,
+			UUID:              player.UUID,
+			PlaceDistribution: make([]float64, len(players)),
+		}
+		for place := range prediction.PlaceDistribution {
+			count := 0
+			for _, tally := range tallies {
+				count += tally.places[i][place]
+			}
+			prediction.PlaceDistribution[place] = float64(count) / float64(runs)
+			prediction.ExpectedPlace += float64(place+1) * prediction.PlaceDistribution[place]
+		}
+		prediction.WinProbability = prediction.PlaceDistribution[0]
+		dnfs := 0
+		for _, tally := range tallies {
+			dnfs += tally.dnfs[i]
+		}
+		prediction.DNFRate = float64(dnfs) / float64(runs)
+		result.Players[i] = prediction
+	}
+
+	return result
+}
@@ -0,0 +1,29 @@
+package simulation
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPredictionCountsDNFPlaces(t *testing.T) {
+	// nobody gets near the line before the race is called off, so every
+	// place goes to a DNF
+	result := EstimateWinProbabilities(field(), RaceConfig{TotalDistance: 1000, Seed: 1, MaxRounds: 3}, 200)
+	if result.CalledOff != result.Runs {
+		t.Fatalf("%d of %d races called off, want all of them", result.CalledOff, result.Runs)
+	}
+	expected := 0.0
+	for _, prediction := range result.Players {
+		total := 0.0
+		for _, chance := range prediction.PlaceDistribution {
+			total += chance
+		}
+		if math.Abs(total-1) > 1e-9 || prediction.DNFRate != 1 {
+			t.Errorf("%s: places add up to %v with DNF rate %v, want 1 and 1", prediction.Name, total, prediction.DNFRate)
+		}
+		expected += prediction.ExpectedPlace
+	}
+	if math.Abs(expected-6) > 1e-9 {
+		t.Errorf("expected places add up to %v, want 1+2+3", expected)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"strconv"
 	"math/rand"
+	"strings"
 	"fyne.io/fyne/v2"
 )
 
@@ -25,10 +26,12 @@ func ReadCSV(filename string) ([]Player, error) {
 
 	var players []Player
 	for _, record := range records[1:] { // Skipping the header in the CSV file
-		score, _ := strconv.Atoi(record[1]) // Convert score from string to int
+		score, _ := strconv.ParseFloat(record[1], 64) // Convert score from string to float, dead heats can split points
 		minSpeed, _ := strconv.ParseFloat(record[2], 64)
 		maxSpeed, _ := strconv.ParseFloat(record[3], 64)
-		players = append(players, Player{Name: record[0], Score: score, MinSpeed: minSpeed, MaxSpeed: maxSpeed, UUID: record[4]})
+		stamina, fatigueRate, recoveryRate := recordEnduranceAttributes(record, minSpeed)
+		players = append(players, Player{Name: record[0], Score: score, MinSpeed: minSpeed, MaxSpeed: maxSpeed, UUID: record[4],
+			Stamina: stamina, FatigueRate: fatigueRate, RecoveryRate: recoveryRate, Affinities: recordAffinities(record), EventChances: recordEventChances(record), Strategy: recordStrategy(record), Script: recordScript(record)})
 	}
 	
 	return players, nil
@@ -42,10 +45,27 @@ type Player struct {
     Place       int
     MinSpeed    float64
     MaxSpeed    float64
-    Score       int
+    Score       float64
     UUID        string
     Endurance   float64
     Resting     bool
+    Stamina      float64 // endurance at the start of a race
+    FatigueRate  float64 // endurance used per metre run
+    RecoveryRate float64 // endurance regained per round of rest
+    FinishTime   float64 // rounds taken to cross the line, including the part of the last round
+    RoundsLed    int     // rounds spent in front at the end of the round
+    Bonus        float64 // bonus points included in Score
+    Status       string  // StatusFinished or StatusDNF once the race is over
+    EliminatedRound int  // round an elimination race knocked them out, 0 if it didn't
+    Handicap     float64 // head start in metres
+    Team         []Player  // a relay lane's animals in running order, empty for a single animal
+    Leg          int       // which of the team is running now
+    Splits       []float64 // round each relay leg ended on, including the part of the round
+    Affinities   map[string]float64 // speed multiplier by weather, weather not in it makes no difference
+    EventChances map[string]float64 // chance each round of each random event, missing ones use DefaultEventChances
+    Napping      int                // rounds of a nap still to sleep through
+    Strategy     string             // pacing strategy, blank runs flat out
+    Script       string             // racing rules that override the strategy, see Script
 }
 
 
@@ -64,15 +84,24 @@ func CreatePlayers(playerData [][]string) ([]Player, error) {
 			return nil, fmt.Errorf("invalid max speed for player %s: %v", data[0], err)
 		}
 
+		stamina, fatigueRate, recoveryRate := recordEnduranceAttributes(data, minSpeed)
+
 		player := Player{
-			Name:     data[0],
-			MinSpeed: minSpeed,
-			MaxSpeed: maxSpeed,
-			UUID:     data[4],
-			Distance: 0,
-			Finished: false,
-			Place:    0,
-			Score:    0,
+			Name:         data[0],
+			MinSpeed:     minSpeed,
+			MaxSpeed:     maxSpeed,
+			UUID:         data[4],
+			Distance:     0,
+			Finished:     false,
+			Place:        0,
+			Score:        0,
+			Stamina:      stamina,
+			FatigueRate:  fatigueRate,
+			RecoveryRate: recoveryRate,
+			Affinities:   recordAffinities(data),
+			EventChances: recordEventChances(data),
+			Strategy:     recordStrategy(data),
+			Script:       recordScript(data),
 		}
 		players = append(players, player)
 	}
@@ -80,23 +109,95 @@ func CreatePlayers(playerData [][]string) ([]Player, error) {
 	return players, nil
 }
 
-func RunSimulation(app fyne.App, numberOfPlayers int, laneHeight int, windowWidth int, playerData [][]string, raceLengthEntry string) {
+// RunSimulation opens the race window for the selected players
+func RunSimulation(app fyne.App, numberOfPlayers int, laneHeight int, windowWidth int, playerData [][]string, config RaceConfig) error {
 	// Convert playerData to []Player
 	players, err := CreatePlayers(playerData[1:])
 	if err != nil {
-		return
+		return err
+	}
+
+	if err := config.Validate(); err != nil {
+		return err
+	}
+
+	// Start the race with the created players, recording every round so it can be replayed
+	config.Telemetry = true
+	DrawRaceTrack(app, numberOfPlayers, laneHeight, float32(windowWidth), players, config)
+	return nil
+}
+
+// RunRelaySimulation opens the race window for a relay, one lane per team
+func RunRelaySimulation(app fyne.App, teams []Team, laneHeight int, windowWidth int, config RaceConfig) error {
+	if err := config.Validate(); err != nil {
+		return err
 	}
+	roster, err := ReadCSV("data/animal.simulation")
+	if err != nil {
+		return err
+	}
+	players, err := TeamPlayers(teams, roster)
+	if err != nil {
+		return err
+	}
+
+	config.Mode = ModeRelay
+	config.Telemetry = true
+	DrawRaceTrack(app, len(players), laneHeight, float32(windowWidth), players, config)
+	return nil
+}
+
+// ParseRaceConfig builds a race config from the race setup boxes, the race
+// length can be left blank for a timed race so call Validate once the mode is set
+func ParseRaceConfig(raceLengthEntry string, seedEntry string) (RaceConfig, error) {
+	var config RaceConfig
 
 	// Convert race length from string to int, and handle any potential error
-	raceLength, err := strconv.Atoi(raceLengthEntry)
+	raceLength := 0
+	if strings.TrimSpace(raceLengthEntry) != "" {
+		var err error
+		raceLength, err = strconv.Atoi(strings.TrimSpace(raceLengthEntry))
+		if err != nil || raceLength <= 0 {
+			return config, fmt.Errorf("invalid race length %q", raceLengthEntry)
+		}
+	}
+
+	// Use the seed the user entered, or pick one if they left it blank
+	seed, err := ParseSeed(seedEntry)
 	if err != nil {
-		return
+		return config, err
 	}
 
-	// Start the race with the created players and parsed race length
-	DrawRaceTrack(app, numberOfPlayers, laneHeight, float32(windowWidth),players, raceLength)
+	return RaceConfig{TotalDistance: raceLength, Seed: seed, Model: DefaultMovementModel}, nil
+}
+
+// ParseRounds reads a number of rounds from the race setup, like how long a
+// timed race lasts or how often an elimination race knocks an animal out,
+// blank means 0 which Validate rejects for the modes that need it
+func ParseRounds(roundsEntry string) (int, error) {
+	if strings.TrimSpace(roundsEntry) == "" {
+		return 0, nil
+	}
+	rounds, err := strconv.Atoi(strings.TrimSpace(roundsEntry))
+	if err != nil || rounds <= 0 {
+		return 0, fmt.Errorf("invalid number of rounds %q", roundsEntry)
+	}
+	return rounds, nil
+}
+
+// ParseSeed turns the seed box text into a seed, blank means a random one
+func ParseSeed(seedEntry string) (int64, error) {
+	if strings.TrimSpace(seedEntry) == "" {
+		return NewSeed(), nil
+	}
+	seed, err := strconv.ParseInt(strings.TrimSpace(seedEntry), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid seed %q: must be a whole number", seedEntry)
+	}
+	return seed, nil
 }
 
-func RandomFloat(lowerLimit, upperLimit float64) float64 { 
-    return lowerLimit + rand.Float64()*(upperLimit-lowerLimit)
+// RandomFloat picks a number between the limits using the race's own random source
+func RandomFloat(rng *rand.Rand, lowerLimit, upperLimit float64) float64 { 
+    return lowerLimit + rng.Float64()*(upperLimit-lowerLimit)
 }
\ No newline at end of file
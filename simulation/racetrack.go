@@ -2,7 +2,6 @@ package simulation
 //import some stuff
 import (
 	"fmt"
-	"os"
     "log"
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/canvas"
@@ -10,53 +9,77 @@ import (
 	"fyne.io/fyne/v2/widget"
 	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/dialog"
-	"image/color"
-	"time"
-	"math/rand"
 	"sort"
+	"strconv"
+	"strings"
 	"github.com/google/uuid"
     "hareandtortoise/v2/misc"
 )
 
 
-
-var raceRunning bool = true
-
-// score calculation - revered positions last gets 1 point
-func CalculateScores(players []Player, totalDistance int) {
-	numPlayers := len(players)
-	for i, player := range players {
-		if player.Finished {
-			players[i].Score = (numPlayers - player.Place)+1
-		}
-	}
-}
-
-
 // Modify ShowRaceResultsWindow to include a "Save Race" button
-func ShowRaceResultsWindow(app fyne.App, players []Player, mainWindow fyne.Window, totalDistance, numRounds int) {
+func ShowRaceResultsWindow(app fyne.App, race *Race, mainWindow fyne.Window) {
     if err := misc.Cheering(); err != nil {
 		log.Fatal(err)
 	}
 	resultsWindow := app.NewWindow("Race Results")
 	resultsContainer := container.NewVBox()
 
-	sort.Slice(players, func(i, j int) bool {
-		return players[i].Place < players[j].Place
+	// sort lane numbers by place so the race keeps its lane order for saving
+	players := race.Players
+	order := make([]int, len(players))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return players[order[a]].Place < players[order[b]].Place
 	})
 
-	for i, player := range players {
-		if player.Finished {
-			result := fmt.Sprintf("Place: %d - %s - Score: %d", player.Place, player.Name, players[i].Score)
+	for _, i := range order {
+		player := players[i]
+		switch player.Status {
+		case StatusFinished:
+			if race.Mode == ModeTimed {
+				result := fmt.Sprintf("Place: %s - %s - %.1f covered in %d rounds - Score: %g", race.Placing(i), player.Name, player.Distance, race.RoundLimit, player.Score)
+				resultsContainer.Add(canvas.NewText(result, theme.ForegroundColor()))
+				continue
+			}
+			if race.Mode == ModeElimination {
+				result := fmt.Sprintf("Place: %s - %s - last one standing - Score: %g", race.Placing(i), player.Name, player.Score)
+				if player.EliminatedRound > 0 {
+					result = fmt.Sprintf("Place: %s - %s - knocked out in round %d - Score: %g", race.Placing(i), player.Name, player.EliminatedRound, player.Score)
+				}
+				resultsContainer.Add(canvas.NewText(result, theme.ForegroundColor()))
+				continue
+			}
+			result := fmt.Sprintf("Place: %s - %s - %.2f rounds - Score: %g", race.Placing(i), player.Name, player.FinishTime, player.Score)
+			if len(player.Splits) > 0 {
+				result += " - legs " + strings.ReplaceAll(legSplits(player), ";", " / ")
+			}
+			if player.Handicap > 0 {
+				result += " - " + FormatHandicap(player.Handicap)
+			}
+			resultLabel := canvas.NewText(result, theme.ForegroundColor())
+			resultsContainer.Add(resultLabel)
+		case StatusDNF:
+			result := fmt.Sprintf("Place: %s - %s - %.1f/%d covered - Score: %g", race.Placing(i), player.Name, player.Distance, race.TotalDistance, player.Score)
 			resultLabel := canvas.NewText(result, theme.ForegroundColor())
 			resultsContainer.Add(resultLabel)
 		}
 	}
 
+	seedLabel := canvas.NewText(fmt.Sprintf("Seed: %d - %s model - %s race - %s handicap - %s track - %s", race.Seed, race.Model.Name(), race.Mode, race.Handicap, race.Track.DisplayName(), race.Weather), theme.ForegroundColor())
+	resultsContainer.Add(seedLabel)
+	scoringText := fmt.Sprintf("%s scoring (%s) - %s points for dead heats", race.Scoring.Name, race.Scoring, race.TiePolicy)
+	if race.LeadBonus {
+		scoringText += fmt.Sprintf(" - %g bonus for leading most rounds", LeadBonusPoints)
+	}
+	resultsContainer.Add(canvas.NewText(scoringText, theme.ForegroundColor()))
+
 	// Add "Save Race" button
 	saveButton := widget.NewButton("Save Race", func() {
 		raceUUID := uuid.New().String()
-		SaveRaceResults(players, totalDistance, numRounds, raceUUID)
+		SaveRaceResults(race, raceUUID)
         dialog.NewConfirm("Race saved", "Do you want to report the race to the remote server", 
         func(confirmed bool) {
             if confirmed {
@@ -74,164 +97,82 @@ func ShowRaceResultsWindow(app fyne.App, players []Player, mainWindow fyne.Windo
 	resultsWindow.Show()
 }
 
-//function that does the ui and simulation part of the program
-func DrawRaceTrack(myApp fyne.App, numLanes int, laneHeight int, windowWidth float32, players []Player, totalDistance int) {
-    mainWindow := myApp.NewWindow("Race Simulation")
-	var roundNumber int = 1
-    trackContainer := container.NewWithoutLayout()
-    windowHeight := float32(numLanes) * float32(laneHeight)
+//function that draws the race track and renders the race engine round by round
+func DrawRaceTrack(myApp fyne.App, numLanes int, laneHeight int, windowWidth float32, players []Player, config RaceConfig) {
+    drawRaceTrack(myApp, "Race Simulation", numLanes, laneHeight, windowWidth, players, config, func(race *Race, mainWindow fyne.Window) {
+        ShowRaceResultsWindow(myApp, race, mainWindow)
+    })
+}
 
-    // Initialize endurance for each player (endurance starts full)
-    for i := range players {
-        players[i].Endurance = 100 // Example starting endurance, could be different depending on player
-        players[i].Resting = false // Not resting at start
-    }
+// drawRaceTrack runs a race in its own window and hands the scored race to
+// onFinish when it is over, it isn't called if the window is closed first
+func drawRaceTrack(myApp fyne.App, title string, numLanes int, laneHeight int, windowWidth float32, players []Player, config RaceConfig, onFinish func(*Race, fyne.Window)) {
+    mainWindow := myApp.NewWindow(title)
+    race := NewRace(players, config)
+    totalDistance := race.TotalDistance
+    track := newRaceTrack(players, laneHeight, windowWidth, totalDistance, race.Track)
+    windowHeight := float32(numLanes) * float32(laneHeight)
 
     // Display round number
-    roundText := canvas.NewText(fmt.Sprintf("Round: %d", roundNumber), theme.ForegroundColor())
+    roundText := canvas.NewText(roundLabel(race, race.Round+1), theme.ForegroundColor())
     roundText.TextSize = 24
     roundText.Move(fyne.NewPos(windowWidth/2-50, 10))
+    weatherText := canvas.NewText("Weather: "+race.Weather, theme.ForegroundColor())
+    weatherText.TextSize = 24
 
-    lightGreen := color.RGBA{34, 139, 34, 255}
-    darkGreen := color.RGBA{0, 100, 0, 255}
-
-    playerProgressTexts := make([]*canvas.Text, len(players)) // Create array for progress text
-
-    for i := 0; i < numLanes; i++ {
-        laneColor := lightGreen
-        if i%2 == 1 {
-            laneColor = darkGreen
-        }
-        lane := canvas.NewRectangle(laneColor)
-        lane.Resize(fyne.NewSize(float32(windowWidth), float32(laneHeight)))
-        lane.Move(fyne.NewPos(0, float32(laneHeight)*float32(i)))
-        trackContainer.Add(lane)
-
-        // Display player names and distance travelled at the beginning of lanes
-        playerNameText := canvas.NewText(players[i].Name, theme.ForegroundColor())
-        playerNameText.TextSize = 18
-        playerNameText.Move(fyne.NewPos(10, float32(laneHeight*i)+5))
-        trackContainer.Add(playerNameText)
-
-        // Distance text
-        progressText := canvas.NewText(fmt.Sprintf("0.0/%d", totalDistance), theme.ForegroundColor())
-        progressText.TextSize = 18
-        progressText.Move(fyne.NewPos(150, float32(laneHeight*i)+5))
-        playerProgressTexts[i] = progressText
-        trackContainer.Add(progressText)
-    }
-
-    playerImages := make([]*canvas.Image, len(players))
-    for i := 0; i < numLanes; i++ {
-        imagePath := fmt.Sprintf("data/%s.png", players[i].UUID)
-
-        // Check if the image exists, if not use default.png
-        if _, err := os.Stat(imagePath); os.IsNotExist(err) {
-            fmt.Printf("Image for %s not found, using default.png\n", players[i].Name)
-            imagePath = "data/default.png"
-        } else {
-            fmt.Printf("Using image for %s: %s\n", players[i].Name, imagePath)
-        }
-
-        animal := canvas.NewImageFromFile(imagePath)
-        animal.Resize(fyne.NewSize(50, 50))
-        initialPos := fyne.NewPos(0, float32(laneHeight*i+laneHeight/2)-25)
-        animal.Move(initialPos)
-        playerImages[i] = animal
-        trackContainer.Add(animal)
-    }
-
-    rand.Seed(time.Now().UnixNano())
-
-    for i := range players {
-        players[i].Distance = 0
-        players[i].Finished = false
-        players[i].Place = 0
-    }
-
-    finishedPlayers := 0
-    currentPlace := 1
+    // this window's own run state, closing the window stops the race
+    runner := newRaceRunner(race)
+    mainWindow.SetOnClosed(runner.stop)
 
     // Add start, stop, and end buttons
     startButton := widget.NewButton("Start Race", func() {
-        raceRunning = true
+        runner.setRunning(true)
     })
     stopButton := widget.NewButton("Pause Race", func() {
-        raceRunning = false
+        runner.setRunning(false)
     })
     endButton := widget.NewButton("End Race", func() {
         dialog.NewConfirm("Are you sure?", "Are you sure you want to end the race?", 
         func(confirmed bool) {
             if confirmed {
-                finishedPlayers = len(players)
-				raceRunning = false
+                runner.end()
                 mainWindow.Close()
             }
         }, mainWindow).Show()
     })
 
-    buttonContainer := container.NewHBox(startButton, stopButton, endButton, roundText)
-    layout := container.NewVBox(buttonContainer, trackContainer)
+    // speed controls, step only does anything while the race is paused
+    stepButton := widget.NewButton("Step", func() {
+        runner.stepOnce()
+    })
+    instantButton := widget.NewButton("Instant Finish", func() {
+        runner.finishInstantly()
+    })
+    speedSelect := widget.NewSelect(RaceSpeeds, func(selected string) {
+        speed, err := strconv.ParseFloat(strings.TrimSuffix(selected, "x"), 64)
+        if err == nil {
+            runner.setSpeed(speed)
+        }
+    })
+    speedSelect.SetSelected("1x")
+
+    buttonContainer := container.NewHBox(startButton, stopButton, stepButton, speedSelect, instantButton, endButton, roundText, weatherText)
+    layout := container.NewVBox(buttonContainer, track.content)
     // simulation loop
     go func() {
-		raceRunning = true
-        for finishedPlayers < len(players) {
-            if raceRunning {
-                roundNumber++ // Increment round number
-                roundText.Text = fmt.Sprintf("Round: %d", roundNumber) // Update round number display
-                canvas.Refresh(roundText)
-
-                for i := range players {
-                    if players[i].Finished {
-                        continue // Skip finished players
-                    }
-
-                    if players[i].Resting {
-                        // Recover endurance and skip this round
-                        players[i].Endurance += 3 * players[i].MinSpeed
-                        players[i].Resting = false
-                        continue
-                    }
-
-                    // Deduct endurance based on the distance run this round
-                    distanceRun := RandomFloat(players[i].MinSpeed, players[i].MaxSpeed)
-                    players[i].Endurance -= distanceRun
-
-                    if players[i].Endurance <= 0 {
-                        players[i].Endurance = 0
-                        players[i].Resting = true
-                        continue
-                    }
-
-                    // Move player if not resting
-                    players[i].Distance += distanceRun
-                    playerProgress := (players[i].Distance / float64(totalDistance)) * float64(windowWidth-50)
-                    if playerProgress > float64(windowWidth-50) {
-                        playerProgress = float64(windowWidth - 50)
-                    }
-                    newPos := fyne.NewPos(float32(playerProgress), float32(laneHeight*i+laneHeight/2)-25)
-                    playerImages[i].Move(newPos)
-                    canvas.Refresh(playerImages[i])
-
-                    // Update distance travelled text
-                    playerProgressTexts[i].Text = fmt.Sprintf("%.1f/%d", players[i].Distance, totalDistance)
-                    canvas.Refresh(playerProgressTexts[i])
-
-                    if players[i].Distance >= float64(totalDistance) {
-                        players[i].Finished = true
-                        players[i].Place = currentPlace
-                        currentPlace++
-                        finishedPlayers++
-                        playerImages[i].Move(fyne.NewPos(float32(windowWidth-50), float32(laneHeight*i+laneHeight/2)-25))
-                        canvas.Refresh(playerImages[i])
-                    }
-                }
-            }
-            time.Sleep(100 * time.Millisecond)
+        finished := runner.run(config.Tick, func(state RoundState) {
+            roundText.Text = roundLabel(race, state.Round+1) // Update round number display
+            canvas.Refresh(roundText)
+
+            // draw every lane as an instant finish skips straight to the last round
+            track.render(state)
+        })
+        if !finished {
+            return // the window was closed so the race was abandoned
         }
 
-        CalculateScores(players, totalDistance)
-        ShowRaceResultsWindow(myApp, players, mainWindow, totalDistance, roundNumber)
+        CalculateScores(race)
+        onFinish(race, mainWindow)
         mainWindow.Close()
     }()
 
@@ -240,3 +181,20 @@ func DrawRaceTrack(myApp fyne.App, numLanes int, laneHeight int, windowWidth flo
     mainWindow.CenterOnScreen()
     mainWindow.Show()
 }
+
+// roundLabel is the round counter text, timed races show how many rounds they
+// last and elimination races how long until the next knockout
+func roundLabel(race *Race, round int) string {
+    switch race.Mode {
+    case ModeTimed:
+        if round > race.RoundLimit {
+            round = race.RoundLimit
+        }
+        return fmt.Sprintf("Round: %d/%d", round, race.RoundLimit)
+    case ModeElimination:
+        // count down to the next knockout
+        next := race.EliminationInterval - (round-1)%race.EliminationInterval
+        return fmt.Sprintf("Round: %d - knockout in %d", round, next)
+    }
+    return fmt.Sprintf("Round: %d", round)
+}
@@ -0,0 +1,254 @@
+package simulation
+//import some stuff
+import (
+	"encoding/csv"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ratings are Elo stretched to races with more than two animals, every pair
+// in a race counts as a head to head that the animal placed ahead wins (a
+// shared place is a draw) and each animal's change is averaged over the field
+const (
+	DefaultRating = 1500.0 // what an animal starts on before its first race
+	RatingK       = 32.0   // the most a rating can move in one race
+	ratingScale   = 400.0  // a rating gap this big makes the higher animal 10 times more likely to win
+)
+
+// RatingsFile keeps every rating change, so the latest entry for an animal is its current rating
+const RatingsFile = "data/ratings.csv"
+
+// RatingHeader is the header row of the ratings file
+var RatingHeader = []string{"UUID", "Race", "Date", "Rating", "Change"}
+
+// RatingEntry is an animal's rating after one race
+type RatingEntry struct {
+	UUID   string
+	Race   string // the race UUID
+	Date   string
+	Rating float64
+	Change float64
+}
+
+// ratedResult is where an animal came in a race, all rating needs to know
+type ratedResult struct {
+	UUID  string
+	Place int
+}
+
+// expectedScore is the chance of an animal rated a beating one rated b
+func expectedScore(a, b float64) float64 {
+	return 1 / (1 + math.Pow(10, (b-a)/ratingScale))
+}
+
+// ratingChanges works out how much each animal's rating moves after a race,
+// animals that weren't placed are left out
+func ratingChanges(results []ratedResult, ratings map[string]float64) map[string]float64 {
+	var placed []ratedResult
+	for _, result := range results {
+		if result.Place > 0 {
+			placed = append(placed, result)
+		}
+	}
+	changes := make(map[string]float64)
+	if len(placed) < 2 {
+		return changes
+	}
+
+	rating := func(animalUUID string) float64 {
+		if r, ok := ratings[animalUUID]; ok {
+			return r
+		}
+		return DefaultRating
+	}
+	for _, result := range placed {
+		actual, expected := 0.0, 0.0
+		for _, other := range placed {
+			if other.UUID == result.UUID {
+				continue
+			}
+			switch {
+			case result.Place < other.Place:
+				actual++
+			case result.Place == other.Place:
+				actual += 0.5
+			}
+			expected += expectedScore(rating(result.UUID), rating(other.UUID))
+		}
+		changes[result.UUID] = RatingK * (actual - expected) / float64(len(placed)-1)
+	}
+	return changes
+}
+
+// rateRace applies a race's rating changes to ratings and gives the history entries for it
+func rateRace(raceUUID, date string, results []ratedResult, ratings map[string]float64) []RatingEntry {
+	changes := ratingChanges(results, ratings)
+	var entries []RatingEntry
+	for _, result := range results {
+		change, ok := changes[result.UUID]
+		if !ok {
+			continue
+		}
+		before, ok := ratings[result.UUID]
+		if !ok {
+			before = DefaultRating
+		}
+		ratings[result.UUID] = before + change
+		entries = append(entries, RatingEntry{UUID: result.UUID, Race: raceUUID, Date: date, Rating: before + change, Change: change})
+	}
+	return entries
+}
+
+// ReadRatingHistory reads every rating change in the order they happened, a
+// missing file just means no race has been rated yet
+func ReadRatingHistory(filename string) ([]RatingEntry, error) {
+	file, err := os.Open(filename)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	var history []RatingEntry
+	for i, record := range records {
+		if i == 0 {
+			continue // header
+		}
+		if len(record) < len(RatingHeader) {
+			return nil, fmt.Errorf("invalid rating in record %d", i)
+		}
+		rating, err := strconv.ParseFloat(record[3], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rating in record %d: %v", i, err)
+		}
+		change, _ := strconv.ParseFloat(record[4], 64)
+		history = append(history, RatingEntry{UUID: record[0], Race: record[1], Date: record[2], Rating: rating, Change: change})
+	}
+	return history, nil
+}
+
+// CurrentRatings is each animal's latest rating, animals that haven't raced aren't in it
+func CurrentRatings(history []RatingEntry) map[string]float64 {
+	ratings := make(map[string]float64)
+	for _, entry := range history {
+		ratings[entry.UUID] = entry.Rating
+	}
+	return ratings
+}
+
+// ratingRecord turns a history entry into a ratings file row
+func ratingRecord(entry RatingEntry) []string {
+	return []string{
+		entry.UUID,
+		entry.Race,
+		entry.Date,
+		strconv.FormatFloat(entry.Rating, 'f', -1, 64),
+		strconv.FormatFloat(entry.Change, 'f', -1, 64),
+	}
+}
+
+// UpdateRatings rates a saved race and adds the changes to the ratings file.
+// Relay races are left out as their lanes are teams, not animals.
+func UpdateRatings(race *Race, raceUUID string, date string) error {
+	if race.Mode == ModeRelay {
+		return nil
+	}
+	history, err := ReadRatingHistory(RatingsFile)
+	if err != nil {
+		return err
+	}
+
+	results := make([]ratedResult, len(race.Players))
+	for i, player := range race.Players {
+		results[i] = ratedResult{UUID: player.UUID, Place: player.Place}
+	}
+	entries := rateRace(raceUUID, date, results, CurrentRatings(history))
+	if len(entries) == 0 {
+		return nil
+	}
+
+	var data [][]string
+	if _, err := os.Stat(RatingsFile); os.IsNotExist(err) {
+		data = append(data, RatingHeader)
+	}
+	for _, entry := range entries {
+		data = append(data, ratingRecord(entry))
+	}
+	return WriteCSV(RatingsFile, data, true)
+}
+
+// savedRace is a race read back from its file for rating
+type savedRace struct {
+	UUID    string
+	Date    string
+	Results []ratedResult
+}
+
+// readSavedRaces reads the results of every saved race, oldest first
+func readSavedRaces() ([]savedRace, error) {
+	// every race is a .simulation file, so teams, tournaments, seasons and the
+	// rest of data/ use other extensions to stay out of the race listings
+	paths, err := filepath.Glob(filepath.Join("data", "*.simulation"))
+	if err != nil {
+		return nil, err
+	}
+
+	var races []savedRace
+	for _, path := range paths {
+		raceUUID := strings.TrimSuffix(filepath.Base(path), ".simulation")
+		if raceUUID == "animal" {
+			continue // the roster, not a race
+		}
+		records, columns, err := readRaceFile(raceUUID)
+		if err != nil {
+			return nil, err
+		}
+		if Column(records[0], columns, "Mode") == ModeRelay {
+			continue
+		}
+
+		race := savedRace{UUID: raceUUID, Date: Column(records[0], columns, "Date") + " " + Column(records[0], columns, "Time")}
+		for _, record := range records {
+			place, err := strconv.Atoi(Column(record, columns, "Place"))
+			if err != nil {
+				return nil, fmt.Errorf("invalid place in race %s: %v", raceUUID, err)
+			}
+			race.Results = append(race.Results, ratedResult{UUID: Column(record, columns, "UUID"), Place: place})
+		}
+		races = append(races, race)
+	}
+	sort.SliceStable(races, func(a, b int) bool {
+		return races[a].Date < races[b].Date
+	})
+	return races, nil
+}
+
+// RecomputeRatings starts every animal again from DefaultRating and replays
+// every saved race in date order, replacing the ratings file
+func RecomputeRatings() error {
+	races, err := readSavedRaces()
+	if err != nil {
+		return err
+	}
+
+	ratings := make(map[string]float64)
+	data := [][]string{RatingHeader}
+	for _, race := range races {
+		for _, entry := range rateRace(race.UUID, race.Date, race.Results, ratings) {
+			data = append(data, ratingRecord(entry))
+		}
+	}
+	return WriteCSV(RatingsFile, data, false)
+}
@@ -0,0 +1,76 @@
+package simulation
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestRatingChangesAreZeroSum(t *testing.T) {
+	tests := []struct {
+		name    string
+		results []ratedResult
+		ratings map[string]float64
+	}{
+		{"two new animals", []ratedResult{{"a", 1}, {"b", 2}}, nil},
+		{"favourite wins", []ratedResult{{"a", 1}, {"b", 2}, {"c", 3}}, map[string]float64{"a": 1700, "b": 1500, "c": 1400}},
+		{"upset", []ratedResult{{"a", 3}, {"b", 2}, {"c", 1}}, map[string]float64{"a": 1700, "b": 1500, "c": 1400}},
+		{"dead heat", []ratedResult{{"a", 1}, {"b", 1}, {"c", 3}, {"d", 4}}, map[string]float64{"a": 1550, "d": 1620}},
+		{"unplaced left out", []ratedResult{{"a", 2}, {"b", 1}, {"c", 0}}, map[string]float64{"c": 1800}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			changes := ratingChanges(test.results, test.ratings)
+			total := 0.0
+			for _, change := range changes {
+				total += change
+			}
+			if math.Abs(total) > 1e-9 {
+				t.Errorf("changes %v add up to %v, want 0", changes, total)
+			}
+			for _, result := range test.results {
+				if _, ok := changes[result.UUID]; ok == (result.Place == 0) {
+					t.Errorf("%s placed %d has change %v", result.UUID, result.Place, changes[result.UUID])
+				}
+			}
+		})
+	}
+}
+
+func TestDeadHeatBetweenEqualRatingsChangesNothing(t *testing.T) {
+	for _, ratings := range []map[string]float64{nil, {"a": 1620, "b": 1620}} {
+		changes := ratingChanges([]ratedResult{{"a", 1}, {"b", 1}}, ratings)
+		if changes["a"] != 0 || changes["b"] != 0 {
+			t.Errorf("dead heat between %v moved ratings by %v", ratings, changes)
+		}
+	}
+}
+
+func TestRecomputeMatchesRaceByRaceUpdates(t *testing.T) {
+	inDataDir(t)
+	// each race is rated as it's saved
+	for seed := int64(1); seed <= 3; seed++ {
+		race := NewRace(field(), RaceConfig{TotalDistance: 100, Seed: seed})
+		race.Run()
+		SaveRaceResults(race, fmt.Sprintf("race%d", seed))
+	}
+	updated, err := ReadRatingHistory(RatingsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(updated) != 3*len(field()) {
+		t.Fatalf("got %d rating changes for 3 races, want %d", len(updated), 3*len(field()))
+	}
+
+	if err := RecomputeRatings(); err != nil {
+		t.Fatal(err)
+	}
+	recomputed, err := ReadRatingHistory(RatingsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(recomputed, updated) {
+		t.Errorf("recomputed %+v, want the race by race ratings %+v", recomputed, updated)
+	}
+}
@@ -0,0 +1,176 @@
+package simulation
+//import some stuff
+import (
+	"fmt"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LoadReplay gets every round of a saved race, from its telemetry if that was
+// recorded or by re-running the race from its seed if not
+func LoadReplay(uuid string) ([]Player, []RoundState, int, error) {
+	records, columns, err := readRaceFile(uuid)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	totalDistance, err := strconv.Atoi(Column(records[0], columns, "Total Distance"))
+	if err != nil {
+		return nil, nil, 0, fmt.Errorf("invalid total distance in race %s: %v", uuid, err)
+	}
+
+	players, rounds, err := LoadTelemetry(uuid)
+	if err == nil {
+		// telemetry doesn't keep the head starts or relay teams, the race file has them in lane order
+		for i := range players {
+			if i >= len(records) {
+				break
+			}
+			players[i].Handicap, _ = strconv.ParseFloat(Column(records[i], columns, "Handicap"), 64)
+			if members := Column(records[i], columns, "Team Members"); members != "" {
+				for _, member := range strings.Split(members, ";") {
+					players[i].Team = append(players[i].Team, Player{UUID: member})
+				}
+			}
+		}
+		return players, rounds, totalDistance, nil
+	}
+
+	// no telemetry so fall back to running the race again from its seed
+	players, config, err := LoadRaceSetup(uuid)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	config.Telemetry = true
+	race := NewRace(players, config)
+	race.Run()
+	return race.Players, race.Telemetry, race.TotalDistance, nil
+}
+
+// replayState is shared between the replay controls and the playback loop
+type replayState struct {
+	mu      sync.Mutex
+	playing bool
+	speed   float64
+}
+
+// ShowReplayWindow animates a saved race on the same lanes as the live race
+func ShowReplayWindow(app fyne.App, uuid string) error {
+	players, rounds, totalDistance, err := LoadReplay(uuid)
+	if err != nil {
+		return err
+	}
+	records, columns, err := readRaceFile(uuid)
+	if err != nil {
+		return err
+	}
+	terrain, err := savedTrack(records[0], columns)
+	if err != nil {
+		return fmt.Errorf("invalid track in race %s: %v", uuid, err)
+	}
+	// round 0 is everyone on the start line, or their head start
+	start := RoundState{Players: make([]PlayerState, len(players))}
+	for i, player := range players {
+		start.Players[i].Distance = player.Handicap
+	}
+	rounds = append([]RoundState{start}, rounds...)
+
+	laneHeight := 70
+	var windowWidth float32 = 1000
+	replayWindow := app.NewWindow("Race Replay")
+	track := newRaceTrack(players, laneHeight, windowWidth, totalDistance, terrain)
+	state := &replayState{speed: 1}
+
+	roundText := canvas.NewText("Round: 0", theme.ForegroundColor())
+	roundText.TextSize = 24
+	// races saved before weather was added were run in the sun
+	weather := Column(records[0], columns, "Weather")
+	if weather == "" {
+		weather = WeatherSunny
+	}
+	weatherText := canvas.NewText("Weather: "+weather, theme.ForegroundColor())
+	weatherText.TextSize = 24
+
+	// scrubbing to a round just redraws the lanes as they were in that round
+	scrubber := widget.NewSlider(0, float64(len(rounds)-1))
+	scrubber.Step = 1
+	scrubber.OnChanged = func(value float64) {
+		round := rounds[int(value)]
+		track.render(round)
+		roundText.Text = fmt.Sprintf("Round: %d", round.Round)
+		canvas.Refresh(roundText)
+	}
+
+	var playButton *widget.Button
+	playButton = widget.NewButton("Play", func() {
+		state.mu.Lock()
+		defer state.mu.Unlock()
+		state.playing = !state.playing
+		if state.playing {
+			// start again from the beginning if the replay already ended
+			if int(scrubber.Value) >= len(rounds)-1 {
+				scrubber.SetValue(0)
+			}
+			playButton.SetText("Pause")
+		} else {
+			playButton.SetText("Play")
+		}
+	})
+
+	speedSelect := widget.NewSelect(RaceSpeeds, func(selected string) {
+		speed, err := strconv.ParseFloat(strings.TrimSuffix(selected, "x"), 64)
+		if err != nil {
+			return
+		}
+		state.mu.Lock()
+		state.speed = speed
+		state.mu.Unlock()
+	})
+	speedSelect.SetSelected("1x")
+
+	// playback loop, stops when the window is closed
+	stop := make(chan struct{})
+	replayWindow.SetOnClosed(func() {
+		close(stop)
+	})
+	go func() {
+		for {
+			state.mu.Lock()
+			delay := time.Duration(float64(100*time.Millisecond) / state.speed)
+			state.mu.Unlock()
+
+			select {
+			case <-stop:
+				return
+			case <-time.After(delay):
+			}
+
+			state.mu.Lock()
+			if state.playing {
+				next := int(scrubber.Value) + 1
+				if next < len(rounds) {
+					scrubber.SetValue(float64(next))
+				} else {
+					state.playing = false
+					playButton.SetText("Play")
+				}
+			}
+			state.mu.Unlock()
+		}
+	}()
+
+	controls := container.NewBorder(nil, nil, container.NewHBox(playButton, speedSelect, roundText, weatherText), nil, scrubber)
+	layout := container.NewVBox(controls, track.content)
+
+	replayWindow.SetContent(layout)
+	replayWindow.Resize(fyne.NewSize(windowWidth, track.height()+100))
+	replayWindow.CenterOnScreen()
+	replayWindow.Show()
+	return nil
+}
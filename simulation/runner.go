@@ -0,0 +1,172 @@
+package simulation
+//import some stuff
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DefaultTick is how long the race window waits between rounds at 1x speed
+const DefaultTick = 100 * time.Millisecond
+
+// RaceSpeeds are the speeds offered on the race and replay windows
+var RaceSpeeds = []string{"0.25x", "0.5x", "1x", "2x", "4x", "8x", "16x"}
+
+// raceRunner drives the engine for one race window. Every window gets its own
+// so pausing or closing one race never touches another.
+type raceRunner struct {
+	mu      sync.Mutex // the engine isn't safe to use from two goroutines at once
+	race    *Race
+	running bool
+	speed   float64 // multiplier on the tick
+	steps   int     // single steps asked for while paused
+	instant bool    // run straight to the end without waiting
+	wake    chan struct{}
+	ctx     context.Context
+	cancel  context.CancelFunc
+}
+
+// newRaceRunner wraps a race, it starts off running at 1x
+func newRaceRunner(race *Race) *raceRunner {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &raceRunner{race: race, running: true, speed: 1, wake: make(chan struct{}, 1), ctx: ctx, cancel: cancel}
+}
+
+// poke wakes the run loop so a change takes effect without waiting out the tick
+func (rr *raceRunner) poke() {
+	select {
+	case rr.wake <- struct{}{}:
+	default:
+	}
+}
+
+// setSpeed changes how fast rounds go by, 2 is twice as fast
+func (rr *raceRunner) setSpeed(speed float64) {
+	if speed <= 0 {
+		return
+	}
+	rr.mu.Lock()
+	rr.speed = speed
+	rr.mu.Unlock()
+	rr.poke()
+}
+
+// stepOnce runs a single round while the race is paused
+func (rr *raceRunner) stepOnce() {
+	rr.mu.Lock()
+	if !rr.running {
+		rr.steps++
+	}
+	rr.mu.Unlock()
+	rr.poke()
+}
+
+// finishInstantly runs the rest of the race without waiting between rounds
+func (rr *raceRunner) finishInstantly() {
+	rr.mu.Lock()
+	rr.instant = true
+	rr.mu.Unlock()
+	rr.poke()
+}
+
+// setRunning pauses or resumes the race
+func (rr *raceRunner) setRunning(running bool) {
+	rr.mu.Lock()
+	rr.running = running
+	rr.mu.Unlock()
+	rr.poke()
+}
+
+// next works out whether the loop should run a round now and how long to wait after it
+func (rr *raceRunner) next(tick time.Duration) (bool, time.Duration) {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+	advance := rr.running || rr.steps > 0
+	if !rr.running && rr.steps > 0 {
+		rr.steps--
+	}
+	return advance, time.Duration(float64(tick) / rr.speed)
+}
+
+// step runs one round, ok is false if the race was already over
+func (rr *raceRunner) step() (RoundState, bool) {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+	if rr.race.Done() {
+		return RoundState{}, false
+	}
+	return rr.race.Step(), true
+}
+
+// finish runs every remaining round at once if an instant finish was asked
+// for. It takes the lock a round at a time so the race can still be ended,
+// and stops if the window is closed, the engine's round limit sees to the rest.
+func (rr *raceRunner) finish() (RoundState, bool) {
+	rr.mu.Lock()
+	instant := rr.instant
+	rr.mu.Unlock()
+	if !instant {
+		return RoundState{}, false
+	}
+	var state RoundState
+	stepped := false
+	for rr.ctx.Err() == nil {
+		next, ok := rr.step()
+		if !ok {
+			break
+		}
+		state, stepped = next, true
+	}
+	return state, stepped
+}
+
+// done reports whether the race is over
+func (rr *raceRunner) done() bool {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+	return rr.race.Done()
+}
+
+// end ends the race early, marking anyone still running as DNF
+func (rr *raceRunner) end() {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+	rr.running = false
+	rr.race.End()
+}
+
+// stop abandons the race, the loop in run returns as soon as it notices
+func (rr *raceRunner) stop() {
+	rr.cancel()
+}
+
+// run plays the race one round per tick (divided by the speed) while it is
+// unpaused, calling onRound after each round. It returns true once the race
+// is over, or false if it was stopped first.
+func (rr *raceRunner) run(tick time.Duration, onRound func(RoundState)) bool {
+	if tick <= 0 {
+		tick = DefaultTick
+	}
+	for !rr.done() {
+		if state, ok := rr.finish(); ok {
+			if rr.ctx.Err() != nil {
+				return rr.done()
+			}
+			onRound(state)
+			break
+		}
+		advance, delay := rr.next(tick)
+		if advance {
+			if state, ok := rr.step(); ok {
+				onRound(state)
+			}
+		}
+		select {
+		case <-rr.ctx.Done():
+			return rr.done()
+		case <-rr.wake:
+		case <-time.After(delay):
+		}
+	}
+	return true
+}
@@ -2,9 +2,11 @@ package simulation
 
 import (
 	"encoding/csv"
+	"encoding/json"
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -39,7 +41,7 @@ func SavePlayersToCSV(filename string, players []Player) error {
 		uuid := record[4] // Assuming UUID is the 5th column
 		if updatedPlayer, ok := playerMap[uuid]; ok {
 			// Read the existing score from the CSV (assuming the score is the 2nd column)
-			existingScore, err := strconv.Atoi(record[1])
+			existingScore, err := strconv.ParseFloat(record[1], 64)
 			if err != nil {
 				return fmt.Errorf("invalid score in record %d: %v", i, err)
 			}
@@ -47,14 +49,11 @@ func SavePlayersToCSV(filename string, players []Player) error {
 			// Add the incoming score to the existing score
 			newScore := existingScore + updatedPlayer.Score
 
-			// Replace the player's data with updated values
-			records[i] = []string{
-				updatedPlayer.Name,
-				strconv.Itoa(newScore), // Save the new score
-				strconv.FormatFloat(updatedPlayer.MinSpeed, 'f', -1, 64),
-				strconv.FormatFloat(updatedPlayer.MaxSpeed, 'f', -1, 64),
-				updatedPlayer.UUID,
-			}
+			// Replace the player's data with updated values, keeping any columns after the UUID
+			records[i][0] = updatedPlayer.Name
+			records[i][1] = strconv.FormatFloat(newScore, 'f', -1, 64) // Save the new score
+			records[i][2] = strconv.FormatFloat(updatedPlayer.MinSpeed, 'f', -1, 64)
+			records[i][3] = strconv.FormatFloat(updatedPlayer.MaxSpeed, 'f', -1, 64)
 		}
 	}
 
@@ -81,8 +80,10 @@ func SavePlayersToCSV(filename string, players []Player) error {
 
 
 // Save the race results to a CSV file, updating the existing score
-func SaveRaceResults(players []Player, totalDistance, numRounds int, uuid string) {
+// players are written in lane order so the race can be re-run from its seed
+func SaveRaceResults(race *Race, uuid string) {
 	filePath := fmt.Sprintf("data/%s.simulation", uuid)
+	players := race.Players
 
 	// Get the current date and time
 	currentTime := time.Now().Format("2006-01-02 15:04:05")
@@ -98,27 +99,303 @@ func SaveRaceResults(players []Player, totalDistance, numRounds int, uuid string
 	defer writer.Flush()
 
 	// Write headers, including Date and Time
-	writer.Write([]string{"UUID", "Place", "Distance Travelled", "Score", "Total Distance", "Rounds", "Date", "Time", "Name"})
+	writer.Write([]string{"UUID", "Place", "Distance Travelled", "Score", "Total Distance", "Rounds", "Date", "Time", "Name", "Seed", "Min Speed", "Max Speed", "Model", "Stamina", "Fatigue Rate", "Recovery Rate", "Finish Time", "Placing", "Tie Policy", "Scoring", "Points Table", "Bonus", "Status", "Mode", "Round Limit", "Elimination Interval", "Eliminated Round", "Handicap Method", "Handicap", "Team Members", "Leg Splits", "Track", "Track Segments", "Weather", "Weather Affinity", "Random Events", "Event Chances", "Events", "Strategy", "Script", "Leg Runners"})
 
 	// Write player data
-	for _, player := range players {
+	for i, player := range players {
+		// a relay lane's own attributes are whichever runner was last in it, so
+		// the lane is saved with its first runner's and every runner is kept in Leg Runners
+		attributes := player
+		if len(player.Team) > 0 {
+			attributes = player.Team[0]
+		}
 		record := []string{
 			player.UUID,
 			fmt.Sprintf("%d", player.Place),
 			fmt.Sprintf("%.1f", player.Distance),
-			fmt.Sprintf("%d", player.Score),
-			fmt.Sprintf("%d", totalDistance),
-			fmt.Sprintf("%d", numRounds),
+			strconv.FormatFloat(player.Score, 'f', -1, 64),
+			fmt.Sprintf("%d", race.TotalDistance),
+			fmt.Sprintf("%d", race.Round),
 			currentTime[:10], // Date
 			currentTime[11:], // Time
 			player.Name,
+			strconv.FormatInt(race.Seed, 10),
+			strconv.FormatFloat(attributes.MinSpeed, 'f', -1, 64),
+			strconv.FormatFloat(attributes.MaxSpeed, 'f', -1, 64),
+			race.Model.Name(),
+			strconv.FormatFloat(attributes.Stamina, 'f', -1, 64),
+			strconv.FormatFloat(attributes.FatigueRate, 'f', -1, 64),
+			strconv.FormatFloat(attributes.RecoveryRate, 'f', -1, 64),
+			finishTime(race, player),
+			race.Placing(i),
+			race.TiePolicy,
+			race.Scoring.Name,
+			race.Scoring.String(),
+			strconv.FormatFloat(player.Bonus, 'f', -1, 64),
+			player.Status,
+			race.Mode,
+			strconv.Itoa(race.RoundLimit),
+			strconv.Itoa(race.EliminationInterval),
+			eliminatedRound(player),
+			race.Handicap,
+			strconv.FormatFloat(player.Handicap, 'f', -1, 64),
+			teamMembers(player),
+			legSplits(player),
+			race.Track.DisplayName(),
+			FormatSegments(race.Track.Segments),
+			race.Weather,
+			FormatAffinities(attributes.Affinities),
+			strconv.FormatBool(race.RandomEvents),
+			FormatEventChances(attributes.EventChances),
+			playerEvents(race, i),
+			StrategyName(attributes.Strategy),
+			attributes.Script,
+			legRunners(player),
 		}
 		writer.Write(record)
 	}
 
-	// Update the player scores in "data/animal.simulation"
+	// Save the round by round telemetry next to the race file
+	if err := SaveTelemetry(race, uuid); err != nil {
+		fmt.Println("Error saving telemetry:", err)
+	}
+
+	// relay scores go to the teams, everything else to the animals in "data/animal.simulation"
+	if race.Mode == ModeRelay {
+		if err := SaveTeamScores(TeamsFile, players); err != nil {
+			fmt.Println("Error saving team scores:", err)
+		}
+		return
+	}
 	if err := SavePlayersToCSV("data/animal.simulation", players); err != nil {
 	}
+	if err := UpdateRatings(race, uuid, currentTime); err != nil {
+		fmt.Println("Error updating ratings:", err)
+	}
+
+}
+
+// finishTime formats how many rounds a player took, blank if they never
+// finished or the race was timed so there was no line to cross
+func finishTime(race *Race, player Player) string {
+	if !player.Finished || race.Mode == ModeTimed {
+		return ""
+	}
+	return fmt.Sprintf("%.2f", player.FinishTime)
+}
 
+// teamMembers lists a relay team's animal UUIDs in running order, blank for a single animal
+func teamMembers(player Player) string {
+	members := make([]string, len(player.Team))
+	for i, member := range player.Team {
+		members[i] = member.UUID
+	}
+	return strings.Join(members, ";")
 }
 
+// legRunners writes a relay lane's runners, in leg order, as a JSON list of
+// animal.simulation rows, blank for a single animal
+func legRunners(player Player) string {
+	if len(player.Team) == 0 {
+		return ""
+	}
+	runners := make([][]string, len(player.Team))
+	for i, runner := range player.Team {
+		runners[i] = AnimalRecord(runner)
+	}
+	data, err := json.Marshal(runners)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// parseLegRunners reads back the runners written by legRunners
+func parseLegRunners(text string) ([]Player, error) {
+	var runners [][]string
+	if err := json.Unmarshal([]byte(text), &runners); err != nil {
+		return nil, err
+	}
+	for _, runner := range runners {
+		if len(runner) < len(AnimalHeader) {
+			return nil, fmt.Errorf("leg runner has %d columns, expected %d", len(runner), len(AnimalHeader))
+		}
+	}
+	return CreatePlayers(runners)
+}
+
+// legSplits lists the round each relay leg ended on, blank for a single animal
+func legSplits(player Player) string {
+	splits := make([]string, len(player.Splits))
+	for i, split := range player.Splits {
+		splits[i] = fmt.Sprintf("%.2f", split)
+	}
+	return strings.Join(splits, ";")
+}
+
+// eliminatedRound formats the round a player was knocked out in, blank if they weren't
+func eliminatedRound(player Player) string {
+	if player.EliminatedRound == 0 {
+		return ""
+	}
+	return strconv.Itoa(player.EliminatedRound)
+}
+
+// readRaceFile reads a saved race and maps each header name to its column,
+// older race files are missing the newer columns so always look them up by name
+func readRaceFile(uuid string) ([][]string, map[string]int, error) {
+	file, err := os.Open(fmt.Sprintf("data/%s.simulation", uuid))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(records) < 2 {
+		return nil, nil, fmt.Errorf("no race data found in race %s", uuid)
+	}
+
+	columns := make(map[string]int)
+	for i, name := range records[0] {
+		columns[name] = i
+	}
+	return records[1:], columns, nil
+}
+
+// Column returns the named column of a record, or "" if the file is too old to have it
+func Column(record []string, columns map[string]int, name string) string {
+	i, ok := columns[name]
+	if !ok || i >= len(record) {
+		return ""
+	}
+	return record[i]
+}
+
+// savedTrack reads back the track a race was run on, races saved before
+// tracks were added were all run on the flat
+func savedTrack(record []string, columns map[string]int) (Track, error) {
+	segments, err := ParseSegments(Column(record, columns, "Track Segments"))
+	if err != nil || len(segments) == 0 {
+		return Track{}, err
+	}
+	return Track{Name: Column(record, columns, "Track"), Segments: segments}, nil
+}
+
+// LoadRaceSetup reads back the players and config a saved race was run with,
+// running NewRace with them reproduces the race exactly. Relays saved before
+// the leg runners were kept only match if their teams haven't been edited since.
+func LoadRaceSetup(uuid string) ([]Player, RaceConfig, error) {
+	var config RaceConfig
+	records, columns, err := readRaceFile(uuid)
+	if err != nil {
+		return nil, config, err
+	}
+
+	seed := Column(records[0], columns, "Seed")
+	if seed == "" {
+		return nil, config, fmt.Errorf("race %s was saved without a seed and cannot be re-run", uuid)
+	}
+	config.Seed, err = strconv.ParseInt(seed, 10, 64)
+	if err != nil {
+		return nil, config, fmt.Errorf("invalid seed in race %s: %v", uuid, err)
+	}
+	config.TotalDistance, err = strconv.Atoi(Column(records[0], columns, "Total Distance"))
+	if err != nil {
+		return nil, config, fmt.Errorf("invalid total distance in race %s: %v", uuid, err)
+	}
+	// races saved before movement models were added all used the classic model
+	config.Model = Column(records[0], columns, "Model")
+	if _, err := NewMovementModel(config.Model, 0); err != nil {
+		return nil, config, err
+	}
+	config.TiePolicy = Column(records[0], columns, "Tie Policy")
+	// races saved before timed races were added are all standard races
+	config.Mode = Column(records[0], columns, "Mode")
+	config.RoundLimit, _ = strconv.Atoi(Column(records[0], columns, "Round Limit"))
+	config.EliminationInterval, _ = strconv.Atoi(Column(records[0], columns, "Elimination Interval"))
+	config.Handicap = Column(records[0], columns, "Handicap Method")
+	config.Handicaps = make(map[string]float64)
+	config.Weather = Column(records[0], columns, "Weather")
+	config.RandomEvents = Column(records[0], columns, "Random Events") == "true"
+	config.Track, err = savedTrack(records[0], columns)
+	if err != nil {
+		return nil, config, fmt.Errorf("invalid track in race %s: %v", uuid, err)
+	}
+
+	// relays saved before the leg runners were kept are rebuilt from the roster as it is now
+	var roster []Player
+
+	var players []Player
+	for _, record := range records {
+		minSpeed, err := strconv.ParseFloat(Column(record, columns, "Min Speed"), 64)
+		if err != nil {
+			return nil, config, fmt.Errorf("invalid min speed in race %s: %v", uuid, err)
+		}
+		maxSpeed, err := strconv.ParseFloat(Column(record, columns, "Max Speed"), 64)
+		if err != nil {
+			return nil, config, fmt.Errorf("invalid max speed in race %s: %v", uuid, err)
+		}
+		// races saved before endurance attributes get the defaults from NewRace
+		stamina, _ := strconv.ParseFloat(Column(record, columns, "Stamina"), 64)
+		fatigueRate, _ := strconv.ParseFloat(Column(record, columns, "Fatigue Rate"), 64)
+		recoveryRate, _ := strconv.ParseFloat(Column(record, columns, "Recovery Rate"), 64)
+		// races saved before handicaps had everyone start on the line
+		handicap, _ := strconv.ParseFloat(Column(record, columns, "Handicap"), 64)
+		affinities, err := ParseAffinities(Column(record, columns, "Weather Affinity"))
+		if err != nil {
+			return nil, config, fmt.Errorf("invalid weather affinity in race %s: %v", uuid, err)
+		}
+		eventChances, err := ParseEventChances(Column(record, columns, "Event Chances"))
+		if err != nil {
+			return nil, config, fmt.Errorf("invalid event chances in race %s: %v", uuid, err)
+		}
+		// races saved before pacing strategies were all run flat out
+		strategy := Column(record, columns, "Strategy")
+		if err := ValidateStrategy(strategy); err != nil {
+			return nil, config, fmt.Errorf("invalid strategy in race %s: %v", uuid, err)
+		}
+		script := Column(record, columns, "Script")
+		if err := ValidateScript(script); err != nil {
+			return nil, config, fmt.Errorf("invalid script in race %s: %v", uuid, err)
+		}
+		config.Handicaps[Column(record, columns, "UUID")] = handicap
+		players = append(players, Player{
+			Name:         Column(record, columns, "Name"),
+			UUID:         Column(record, columns, "UUID"),
+			MinSpeed:     minSpeed,
+			MaxSpeed:     maxSpeed,
+			Stamina:      stamina,
+			FatigueRate:  fatigueRate,
+			RecoveryRate: recoveryRate,
+			Affinities:   affinities,
+			EventChances: eventChances,
+			Strategy:     strategy,
+			Script:       script,
+		})
+		if config.Mode == ModeRelay && Column(record, columns, "Leg Runners") != "" {
+			team, err := parseLegRunners(Column(record, columns, "Leg Runners"))
+			if err != nil {
+				return nil, config, fmt.Errorf("invalid leg runners in race %s: %v", uuid, err)
+			}
+			players[len(players)-1].Team = team
+		} else if config.Mode == ModeRelay {
+			if roster == nil {
+				roster, err = ReadCSV("data/animal.simulation")
+				if err != nil {
+					return nil, config, err
+				}
+			}
+			team := Team{Name: Column(record, columns, "Name"), UUID: Column(record, columns, "UUID"),
+				Members: strings.Split(Column(record, columns, "Team Members"), ";")}
+			lanes, err := TeamPlayers([]Team{team}, roster)
+			if err != nil {
+				return nil, config, err
+			}
+			players[len(players)-1].Team = lanes[0].Team
+		}
+	}
+	return players, config, nil
+}
@@ -0,0 +1,194 @@
+package simulation
+//import some stuff
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// tie policies for players who share a place
+const (
+	TiePolicyShared = "Shared" // everyone in the dead heat gets the points for the place they share
+	TiePolicySplit  = "Split"  // the points for the places they cover are split evenly between them
+)
+
+// DefaultTiePolicy is used when no policy has been picked
+const DefaultTiePolicy = TiePolicyShared
+
+// TiePolicies lists the policies for the settings menu
+var TiePolicies = []string{TiePolicyShared, TiePolicySplit}
+
+// LeadBonusPoints is what the animal that led the most rounds gets when the lead bonus is on
+const LeadBonusPoints = 3.0
+
+// ScoringScheme turns places into points
+type ScoringScheme struct {
+	Name           string
+	Table          []float64 // points for 1st, 2nd, 3rd... places past the end of the table score nothing
+	TopN           int       // only the top N score, N points for 1st down to 1 for Nth
+	WinnerTakesAll bool      // the winner gets a point per animal in the race, everyone else nothing
+}
+
+// built in schemes, linear reversed is the original scoring
+var (
+	LinearScoring         = ScoringScheme{Name: "Linear"}
+	F1Scoring             = ScoringScheme{Name: "F1", Table: []float64{25, 18, 15, 12, 10, 8, 6, 4, 2, 1}}
+	WinnerTakesAllScoring = ScoringScheme{Name: "Winner Takes All", WinnerTakesAll: true}
+	Top3Scoring           = ScoringScheme{Name: "Top 3", TopN: 3}
+)
+
+// ScoringSchemes lists the built in schemes followed by the user's own point tables from the settings
+func ScoringSchemes(customTables map[string][]float64) []ScoringScheme {
+	schemes := []ScoringScheme{LinearScoring, F1Scoring, WinnerTakesAllScoring, Top3Scoring}
+	names := make([]string, 0, len(customTables))
+	for name := range customTables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		schemes = append(schemes, ScoringScheme{Name: name, Table: customTables[name]})
+	}
+	return schemes
+}
+
+// ParsePointsTable reads a points table typed as "10, 6, 4, 2, 1"
+func ParsePointsTable(text string) ([]float64, error) {
+	var table []float64
+	for _, field := range strings.Split(text, ",") {
+		points, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+		if err != nil || points < 0 {
+			return nil, fmt.Errorf("invalid points %q, use numbers separated by commas", strings.TrimSpace(field))
+		}
+		table = append(table, points)
+	}
+	return table, nil
+}
+
+// String writes out how the scheme awards points so saved races stay explainable
+func (s ScoringScheme) String() string {
+	switch {
+	case s.Table != nil:
+		points := make([]string, len(s.Table))
+		for i, value := range s.Table {
+			points[i] = strconv.FormatFloat(value, 'f', -1, 64)
+		}
+		return strings.Join(points, "/")
+	case s.TopN > 0:
+		return fmt.Sprintf("top %d reversed", s.TopN)
+	case s.WinnerTakesAll:
+		return "winner takes all"
+	}
+	return "reversed positions"
+}
+
+// placePoints is how many points a place is worth under the scheme
+func (s ScoringScheme) placePoints(place, numPlayers int) float64 {
+	switch {
+	case s.Table != nil:
+		if place > len(s.Table) {
+			return 0
+		}
+		return s.Table[place-1]
+	case s.TopN > 0:
+		if place > s.TopN {
+			return 0
+		}
+		return float64(s.TopN-place) + 1
+	case s.WinnerTakesAll:
+		if place == 1 {
+			return float64(numPlayers)
+		}
+		return 0
+	}
+	// reversed positions, last gets 1 point
+	return float64(numPlayers-place) + 1
+}
+
+// score calculation using the race's scoring scheme, players in a dead heat
+// are scored using the race's tie policy and DNFs get no points
+func CalculateScores(race *Race) {
+	players := race.Players
+	numPlayers := len(players)
+	scheme := race.Scoring
+	for i, player := range players {
+		if !player.Finished {
+			players[i].Score = 0
+			continue
+		}
+		tied := race.SharedPlace(i)
+		if tied > 1 && race.TiePolicy == TiePolicySplit {
+			// average the points for every place the dead heat covers
+			total := 0.0
+			for place := player.Place; place < player.Place+tied; place++ {
+				total += scheme.placePoints(place, numPlayers)
+			}
+			players[i].Score = total / float64(tied)
+		} else {
+			players[i].Score = scheme.placePoints(player.Place, numPlayers)
+		}
+	}
+
+	// bonus for the finisher who led the most rounds, shared if more than one
+	// did, a DNF gets no points so can't get the bonus either
+	if race.LeadBonus {
+		mostLed := 0
+		for _, player := range players {
+			if player.Finished && player.RoundsLed > mostLed {
+				mostLed = player.RoundsLed
+			}
+		}
+		for i, player := range players {
+			players[i].Bonus = 0
+			if player.Finished && mostLed > 0 && player.RoundsLed == mostLed {
+				players[i].Bonus = LeadBonusPoints
+				players[i].Score += LeadBonusPoints
+			}
+		}
+	}
+}
+
+// SharedPlace is how many players have the same place and status as player i, 1 if no one else does
+func (r *Race) SharedPlace(i int) int {
+	if r.Players[i].Place == 0 {
+		return 0
+	}
+	count := 0
+	for _, other := range r.Players {
+		if other.Status == r.Players[i].Status && other.Place == r.Players[i].Place {
+			count++
+		}
+	}
+	return count
+}
+
+// FormatPlace writes a place as 1st, 2nd, 3rd..., with an = in front if it is shared
+func FormatPlace(place int, shared bool) string {
+	suffix := "th"
+	if place%100 < 11 || place%100 > 13 {
+		switch place % 10 {
+		case 1:
+			suffix = "st"
+		case 2:
+			suffix = "nd"
+		case 3:
+			suffix = "rd"
+		}
+	}
+	if shared {
+		return fmt.Sprintf("=%d%s", place, suffix)
+	}
+	return fmt.Sprintf("%d%s", place, suffix)
+}
+
+// Placing is player i's place written out for the results, e.g. "=2nd" or "DNF (5th)"
+func (r *Race) Placing(i int) string {
+	if r.Players[i].Place == 0 {
+		return ""
+	}
+	placing := FormatPlace(r.Players[i].Place, r.SharedPlace(i) > 1)
+	if r.Players[i].Status == StatusDNF {
+		return fmt.Sprintf("DNF (%s)", placing)
+	}
+	return placing
+}
@@ -0,0 +1,56 @@
+package simulation
+
+import "testing"
+
+func TestDeadHeatPoints(t *testing.T) {
+	tests := []struct {
+		policy                string
+		hare, tortoise, third float64
+	}{
+		// 1st and 2nd are worth 3 and 2 in a field of three
+		{TiePolicyShared, 3, 3, 1},
+		{TiePolicySplit, 2.5, 2.5, 1},
+	}
+	for _, test := range tests {
+		t.Run(test.policy, func(t *testing.T) {
+			// the hare and tortoise dead heat halfway through the third round
+			race := NewRace([]Player{steady("Hare", 1), steady("Tortoise", 4), steady("Fox", 3)}, RaceConfig{TotalDistance: 10, TiePolicy: test.policy})
+			race.Model = scriptedModel{runs: map[string][]float64{"Hare": {5, 0, 10}}}
+			race.Run()
+			CalculateScores(race)
+
+			if race.SharedPlace(0) != 2 {
+				t.Fatalf("SharedPlace = %d, want a two way dead heat", race.SharedPlace(0))
+			}
+			for name, want := range map[string]float64{"Hare": test.hare, "Tortoise": test.tortoise, "Fox": test.third} {
+				if got := playerNamed(t, race, name).Score; got != want {
+					t.Errorf("%s scored %v, want %v", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestDNFsScoreNothing(t *testing.T) {
+	// the tortoise's head start has it leading until the hare crosses the line
+	// in round 10, then the race is ended with the tortoise still out there
+	tortoise, hare := steady("Tortoise", 0.05), steady("Hare", 1)
+	tortoise.Score, hare.Score = 100, 50 // lifetime scores from the roster
+	race := NewRace([]Player{tortoise, hare}, RaceConfig{TotalDistance: 10, LeadBonus: true, Handicaps: map[string]float64{"Tortoise": 9}})
+	for !race.Done() && !playerNamed(t, race, "Hare").Finished {
+		race.Step()
+	}
+	race.End()
+	CalculateScores(race)
+
+	tortoise, hare = playerNamed(t, race, "Tortoise"), playerNamed(t, race, "Hare")
+	if tortoise.RoundsLed <= hare.RoundsLed {
+		t.Fatalf("tortoise led %d rounds and hare %d, the tortoise should have led the most", tortoise.RoundsLed, hare.RoundsLed)
+	}
+	if tortoise.Score != 0 || tortoise.Bonus != 0 {
+		t.Errorf("DNF scored %v with bonus %v, want nothing", tortoise.Score, tortoise.Bonus)
+	}
+	if want := 2 + LeadBonusPoints; hare.Score != want || hare.Bonus != LeadBonusPoints {
+		t.Errorf("winner scored %v with bonus %v, want %v with the lead bonus", hare.Score, hare.Bonus, want)
+	}
+}
@@ -0,0 +1,535 @@
+package simulation
+//import some stuff
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Script is an animal's racing logic, a list of rules read top to bottom
+// where the first one that applies decides the round:
+//
+//	# rest when tired unless it's nearly over
+//	when endurance < 0.3 * stamina and progress < 0.9: rest
+//	when rank > 1 and gap < 20: effort 1
+//	effort 0.7
+//
+// Rules go one per line or are separated by ;, and a rule without a when
+// always applies. effort is a share of the speed range from 0 for min speed
+// to 1 for flat out. Conditions and efforts can use the ScriptVariables,
+// numbers, + - * /, comparisons, and, or, not, brackets and min, max and abs.
+// If no rule applies the animal falls back on its pacing strategy.
+type Script struct {
+	rules []scriptRule
+}
+
+// ScriptVariables are what a script can read about its animal's race
+var ScriptVariables = []string{
+	"distance",  // metres run so far, including any head start
+	"endurance", // endurance left
+	"stamina",   // endurance when fully rested
+	"round",     // the round being run, the first is 1
+	"rank",      // position in the race right now, 1 is leading
+	"gap",       // metres behind the leader, 0 for the leader
+	"total",     // length of the race in metres
+	"progress",  // share of the race run, 0 at the start and 1 at the line
+}
+
+// limits that keep a script from slowing the race down
+const (
+	ScriptStepBudget = 500  // expression steps a script can take each round
+	maxScriptLength  = 4000 // characters in a script
+	maxScriptDepth   = 32   // how deeply brackets and operators can nest
+)
+
+var errStepBudget = errors.New("script ran out of steps")
+
+// scriptRule is one when ...: action line
+type scriptRule struct {
+	condition *scriptNode // nil always applies
+	rest      bool
+	effort    *scriptNode
+}
+
+// scriptNode is a piece of an expression, op is a number, variable, call or operator
+type scriptNode struct {
+	op    string
+	value float64
+	name  string
+	args  []*scriptNode
+}
+
+// scriptFunctions are the functions scripts can call, by how many arguments they take
+var scriptFunctions = map[string]int{"min": 2, "max": 2, "abs": 1}
+
+// ValidateScript checks a script parses, blank means the animal has no script
+func ValidateScript(text string) error {
+	_, err := ParseScript(text)
+	return err
+}
+
+// ParseScript reads a script, blank gives nil as the animal has no script
+func ParseScript(text string) (*Script, error) {
+	if strings.TrimSpace(text) == "" {
+		return nil, nil
+	}
+	if len(text) > maxScriptLength {
+		return nil, fmt.Errorf("script is too long, keep it under %d characters", maxScriptLength)
+	}
+	script := &Script{}
+	steps := 0
+	for n, line := range strings.Split(text, "\n") {
+		if comment := strings.Index(line, "#"); comment >= 0 {
+			line = line[:comment]
+		}
+		for _, part := range strings.Split(line, ";") {
+			if strings.TrimSpace(part) == "" {
+				continue
+			}
+			rule, err := parseRule(part)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", n+1, err)
+			}
+			steps += rule.condition.size() + rule.effort.size()
+			script.rules = append(script.rules, rule)
+		}
+	}
+	// every rule could be looked at in a round, so the whole script has to fit the budget
+	if steps > ScriptStepBudget {
+		return nil, fmt.Errorf("script is too big, it can take up to %d steps a round and the limit is %d", steps, ScriptStepBudget)
+	}
+	return script, nil
+}
+
+// size is how many steps evaluating the expression can take
+func (n *scriptNode) size() int {
+	if n == nil {
+		return 0
+	}
+	size := 1
+	for _, arg := range n.args {
+		size += arg.size()
+	}
+	return size
+}
+
+// scriptParser reads one rule's tokens
+type scriptParser struct {
+	tokens []string
+	pos    int
+	depth  int
+}
+
+func (p *scriptParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *scriptParser) next() string {
+	token := p.peek()
+	p.pos++
+	return token
+}
+
+// parseRule reads [when condition:] rest or [when condition:] effort expression
+func parseRule(text string) (scriptRule, error) {
+	var rule scriptRule
+	tokens, err := scriptTokens(text)
+	if err != nil {
+		return rule, err
+	}
+	p := &scriptParser{tokens: tokens}
+	if p.peek() == "when" {
+		p.next()
+		if rule.condition, err = p.expression(); err != nil {
+			return rule, err
+		}
+		if p.next() != ":" {
+			return rule, fmt.Errorf("expected : after the condition")
+		}
+	}
+	switch action := p.next(); action {
+	case "rest":
+		rule.rest = true
+	case "effort":
+		if rule.effort, err = p.expression(); err != nil {
+			return rule, err
+		}
+	case "":
+		return rule, fmt.Errorf("expected rest or effort")
+	default:
+		return rule, fmt.Errorf("expected rest or effort, found %q", action)
+	}
+	if p.pos < len(p.tokens) {
+		return rule, fmt.Errorf("unexpected %q", p.peek())
+	}
+	return rule, nil
+}
+
+// scriptTokens splits a rule into numbers, words and operators
+func scriptTokens(text string) ([]string, error) {
+	var tokens []string
+	for i := 0; i < len(text); {
+		c := rune(text[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case unicode.IsDigit(c) || c == '.':
+			start := i
+			for i < len(text) && (unicode.IsDigit(rune(text[i])) || text[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, text[start:i])
+		case unicode.IsLetter(c) || c == '_':
+			start := i
+			for i < len(text) && (unicode.IsLetter(rune(text[i])) || unicode.IsDigit(rune(text[i])) || text[i] == '_') {
+				i++
+			}
+			tokens = append(tokens, strings.ToLower(text[start:i]))
+		case strings.ContainsRune("<>=!", c) && i+1 < len(text) && text[i+1] == '=':
+			tokens = append(tokens, text[i:i+2])
+			i += 2
+		case strings.ContainsRune("+-*/()<>:,", c):
+			tokens = append(tokens, string(c))
+			i++
+		default:
+			return nil, fmt.Errorf("unexpected character %q", c)
+		}
+	}
+	return tokens, nil
+}
+
+// expression reads an or of ands, the loosest binding part of an expression
+func (p *scriptParser) expression() (*scriptNode, error) {
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+	return p.binary(0)
+}
+
+// enter counts one more level of nesting, a script nested too deeply is
+// turned away rather than running the parser out of stack
+func (p *scriptParser) enter() error {
+	p.depth++
+	if p.depth > maxScriptDepth {
+		return fmt.Errorf("expression is nested too deeply")
+	}
+	return nil
+}
+
+func (p *scriptParser) leave() {
+	p.depth--
+}
+
+// scriptPrecedence lists the binary operators from loosest to tightest binding
+var scriptPrecedence = [][]string{
+	{"or"},
+	{"and"},
+	{"<", "<=", ">", ">=", "==", "!="},
+	{"+", "-"},
+	{"*", "/"},
+}
+
+// notPrecedence is where not sits, tighter than and but looser than the
+// comparisons so not rank == 1 reads as not (rank == 1)
+const notPrecedence = 2
+
+// binary reads operators at the given precedence level and tighter
+func (p *scriptParser) binary(level int) (*scriptNode, error) {
+	if level == len(scriptPrecedence) {
+		return p.unary()
+	}
+	if level == notPrecedence && p.peek() == "not" {
+		p.next()
+		if err := p.enter(); err != nil {
+			return nil, err
+		}
+		defer p.leave()
+		arg, err := p.binary(level)
+		if err != nil {
+			return nil, err
+		}
+		return &scriptNode{op: "not", args: []*scriptNode{arg}}, nil
+	}
+	left, err := p.binary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := p.peek()
+		found := false
+		for _, candidate := range scriptPrecedence[level] {
+			found = found || op == candidate
+		}
+		if !found {
+			return left, nil
+		}
+		p.next()
+		right, err := p.binary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		left = &scriptNode{op: op, args: []*scriptNode{left, right}}
+	}
+}
+
+// unary reads -x or a single value
+func (p *scriptParser) unary() (*scriptNode, error) {
+	if p.peek() == "-" {
+		p.next()
+		if err := p.enter(); err != nil {
+			return nil, err
+		}
+		defer p.leave()
+		arg, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return &scriptNode{op: "neg", args: []*scriptNode{arg}}, nil
+	}
+	return p.value()
+}
+
+// value reads a number, variable, function call or bracketed expression
+func (p *scriptParser) value() (*scriptNode, error) {
+	token := p.next()
+	switch {
+	case token == "":
+		return nil, fmt.Errorf("expression ends too soon")
+	case token == "(":
+		node, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		if p.next() != ")" {
+			return nil, fmt.Errorf("missing )")
+		}
+		return node, nil
+	case unicode.IsDigit(rune(token[0])) || token[0] == '.':
+		value, err := strconv.ParseFloat(token, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", token)
+		}
+		return &scriptNode{op: "num", value: value}, nil
+	case unicode.IsLetter(rune(token[0])) || token[0] == '_':
+		if arity, ok := scriptFunctions[token]; ok {
+			return p.call(token, arity)
+		}
+		for _, variable := range ScriptVariables {
+			if token == variable {
+				return &scriptNode{op: "var", name: token}, nil
+			}
+		}
+		return nil, fmt.Errorf("unknown name %q, scripts can use %s", token, strings.Join(ScriptVariables, ", "))
+	}
+	return nil, fmt.Errorf("unexpected %q", token)
+}
+
+// call reads the bracketed arguments of a function
+func (p *scriptParser) call(name string, arity int) (*scriptNode, error) {
+	if p.next() != "(" {
+		return nil, fmt.Errorf("expected ( after %s", name)
+	}
+	node := &scriptNode{op: "call", name: name}
+	for len(node.args) < arity {
+		if len(node.args) > 0 && p.next() != "," {
+			return nil, fmt.Errorf("%s takes %d arguments", name, arity)
+		}
+		arg, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		node.args = append(node.args, arg)
+	}
+	if p.next() != ")" {
+		return nil, fmt.Errorf("%s takes %d arguments", name, arity)
+	}
+	return node, nil
+}
+
+// scriptRun is one round's evaluation of a script, counting the steps it takes
+type scriptRun struct {
+	variables map[string]float64
+	steps     int
+}
+
+// truth turns a comparison into a number, true is 1 and false is 0
+func truth(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// eval works out an expression, anything but 0 counts as true
+func (run *scriptRun) eval(n *scriptNode) (float64, error) {
+	run.steps++
+	if run.steps > ScriptStepBudget {
+		return 0, errStepBudget
+	}
+	switch n.op {
+	case "num":
+		return n.value, nil
+	case "var":
+		return run.variables[n.name], nil
+	}
+
+	left, err := run.eval(n.args[0])
+	if err != nil {
+		return 0, err
+	}
+	switch n.op {
+	case "neg":
+		return -left, nil
+	case "not":
+		return truth(left == 0), nil
+	case "and":
+		if left == 0 {
+			return 0, nil
+		}
+	case "or":
+		if left != 0 {
+			return 1, nil
+		}
+	}
+	if len(n.args) == 1 {
+		return math.Abs(left), nil // abs is the only other single argument node
+	}
+	right, err := run.eval(n.args[1])
+	if err != nil {
+		return 0, err
+	}
+	switch n.op {
+	case "and", "or":
+		return truth(right != 0), nil
+	case "+":
+		return left + right, nil
+	case "-":
+		return left - right, nil
+	case "*":
+		return left * right, nil
+	case "/":
+		if right == 0 {
+			return 0, fmt.Errorf("division by zero")
+		}
+		return left / right, nil
+	case "<":
+		return truth(left < right), nil
+	case "<=":
+		return truth(left <= right), nil
+	case ">":
+		return truth(left > right), nil
+	case ">=":
+		return truth(left >= right), nil
+	case "==":
+		return truth(left == right), nil
+	case "!=":
+		return truth(left != right), nil
+	case "call":
+		if n.name == "min" {
+			return math.Min(left, right), nil
+		}
+		return math.Max(left, right), nil
+	}
+	return 0, fmt.Errorf("unknown operator %q", n.op)
+}
+
+// decide runs the rules against the race, matched is false when no rule applied
+func (s *Script) decide(variables map[string]float64) (rest bool, effort float64, matched bool, err error) {
+	run := &scriptRun{variables: variables}
+	for _, rule := range s.rules {
+		if rule.condition != nil {
+			applies, err := run.eval(rule.condition)
+			if err != nil {
+				return false, 0, false, err
+			}
+			if applies == 0 {
+				continue
+			}
+		}
+		if rule.rest {
+			return true, 0, true, nil
+		}
+		effort, err := run.eval(rule.effort)
+		if err != nil {
+			return false, 0, false, err
+		}
+		if math.IsNaN(effort) {
+			return false, 0, false, fmt.Errorf("effort is not a number")
+		}
+		return false, math.Max(0, math.Min(1, effort)), true, nil
+	}
+	return false, 0, false, nil
+}
+
+// script is player i's compiled script, nil if they don't have one. Scripts
+// are compiled once per race, one that won't parse is ignored as they are
+// checked when saved.
+func (r *Race) script(i int) *Script {
+	text := r.Players[i].Script
+	if strings.TrimSpace(text) == "" {
+		return nil
+	}
+	if script, ok := r.scripts[text]; ok {
+		return script
+	}
+	if r.scripts == nil {
+		r.scripts = make(map[string]*Script)
+	}
+	script, _ := ParseScript(text)
+	r.scripts[text] = script
+	return script
+}
+
+// scriptVariables is what player i's script gets to see this round
+func (r *Race) scriptVariables(i int) map[string]float64 {
+	player := r.Players[i]
+	rank, leader := 1, player.Distance
+	for j, other := range r.Players {
+		if j != i && other.Distance > player.Distance {
+			rank++
+		}
+		leader = math.Max(leader, other.Distance)
+	}
+	progress := 0.0
+	if r.TotalDistance > 0 {
+		progress = player.Distance / float64(r.TotalDistance)
+	}
+	return map[string]float64{
+		"distance":  player.Distance,
+		"endurance": player.Endurance,
+		"stamina":   player.Stamina,
+		"round":     float64(r.Round),
+		"rank":      float64(rank),
+		"gap":       leader - player.Distance,
+		"total":     float64(r.TotalDistance),
+		"progress":  progress,
+	}
+}
+
+// decide is whether player i rests this round and otherwise how hard they go.
+// Their script has the first say, a script that doesn't cover the round or
+// goes wrong, like running out of steps, leaves it to their pacing strategy.
+func (r *Race) decide(i int) (rest bool, effort float64) {
+	if script := r.script(i); script != nil {
+		rest, effort, matched, err := script.decide(r.scriptVariables(i))
+		if err == nil && matched {
+			return rest, effort
+		}
+	}
+	return false, r.effort(i)
+}
+
+// recordScript reads the script column of an animal record, older records have none
+func recordScript(record []string) string {
+	if len(record) <= 11 {
+		return ""
+	}
+	return record[11]
+}
@@ -0,0 +1,154 @@
+package simulation
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseScript(t *testing.T) {
+	tests := []struct {
+		name    string
+		script  string
+		wantErr string // "" means it should parse
+	}{
+		{"blank", "", ""},
+		{"effort", "effort 0.5", ""},
+		{"rules and comments", "# tired\nwhen endurance < 0.3 * stamina: rest\nwhen rank > 1 and gap < 20: effort 1; effort 0.7", ""},
+		{"calls", "effort max(0.2, min(1, abs(-progress)))", ""},
+		{"unknown name", "when speed > 1: rest", `unknown name "speed"`},
+		{"no action", "when round > 1:", "expected rest or effort"},
+		{"unknown action", "sprint", `expected rest or effort, found "sprint"`},
+		{"missing colon", "when round > 1 rest", "expected : after the condition"},
+		{"missing effort", "effort", "expression ends too soon"},
+		{"unclosed bracket", "effort (1", "missing )"},
+		{"trailing tokens", "effort 1 2", `unexpected "2"`},
+		{"too few arguments", "effort min(1)", "min takes 2 arguments"},
+		{"bad character", "effort 1 ! 2", "unexpected character '!'"},
+		{"bad number", "effort 1.2.3", `invalid number "1.2.3"`},
+		{"error line", "effort 1\nwhen x: rest", "line 2:"},
+		{"too deep", "effort " + strings.Repeat("(", maxScriptDepth+1) + "1" + strings.Repeat(")", maxScriptDepth+1), "nested too deeply"},
+		{"over budget", "effort " + strings.Repeat("1+", ScriptStepBudget) + "1", "script is too big"},
+		{"too long", "effort " + strings.Repeat(" ", maxScriptLength) + "1", "script is too long"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ParseScript(test.script)
+			if test.wantErr == "" {
+				if err != nil {
+					t.Fatalf("ParseScript(%q) = %v, want no error", test.script, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Fatalf("ParseScript(%q) = %v, want an error containing %q", test.script, err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestScriptDecide(t *testing.T) {
+	variables := map[string]float64{
+		"distance": 40, "endurance": 30, "stamina": 100, "round": 5,
+		"rank": 2, "gap": 10, "total": 100, "progress": 0.4,
+	}
+	tests := []struct {
+		name        string
+		script      string
+		wantRest    bool
+		wantEffort  float64
+		wantMatched bool
+	}{
+		{"always", "effort 0.5", false, 0.5, true},
+		{"rest", "when endurance < 0.5 * stamina: rest", true, 0, true},
+		{"no rule applies", "when round > 10: effort 1", false, 0, false},
+		{"first match wins", "when rank == 2: effort 0.25\neffort 1", false, 0.25, true},
+		{"skips rules that don't apply", "when rank == 1: effort 0.25; effort 0.75", false, 0.75, true},
+		{"multiplication before addition", "effort 0.1 + 0.2 * 2", false, 0.5, true},
+		{"brackets", "effort (0.25 + 0.5) * 0.5", false, 0.375, true},
+		{"left to right", "effort 1 - 0.5 - 0.25", false, 0.25, true},
+		{"and before or", "when rank == 1 and gap > 0 or round == 5: effort 0.5", false, 0.5, true},
+		{"comparison before and", "when gap > 5 and gap < 20: effort 0.5", false, 0.5, true},
+		{"not after comparison", "when not rank == 1: effort 0.5", false, 0.5, true},
+		{"not before and", "when not rank == 2 and gap > 0: effort 0.5; effort 0.25", false, 0.25, true},
+		{"not not", "when not not round: effort 0.5", false, 0.5, true},
+		{"negative", "effort -gap / -20", false, 0.5, true},
+		{"abs", "effort abs(0.2 - progress)", false, 0.2, true},
+		{"min", "effort min(progress, 0.3)", false, 0.3, true},
+		{"max", "effort max(progress, 0.3)", false, 0.4, true},
+		{"clamped high", "effort 3", false, 1, true},
+		{"clamped low", "effort -3", false, 0, true},
+		{"case insensitive", "WHEN Rank == 2: EFFORT 0.5", false, 0.5, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			script, err := ParseScript(test.script)
+			if err != nil {
+				t.Fatalf("ParseScript(%q) = %v", test.script, err)
+			}
+			rest, effort, matched, err := script.decide(variables)
+			if err != nil {
+				t.Fatalf("decide = %v", err)
+			}
+			if rest != test.wantRest || effort != test.wantEffort || matched != test.wantMatched {
+				t.Errorf("decide = (%v, %v, %v), want (%v, %v, %v)", rest, effort, matched, test.wantRest, test.wantEffort, test.wantMatched)
+			}
+		})
+	}
+}
+
+func TestScriptDecideDivisionByZero(t *testing.T) {
+	script, err := ParseScript("when distance / (round - round) > 1: rest\neffort 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, matched, err := script.decide(map[string]float64{"distance": 1}); err == nil || matched {
+		t.Errorf("decide = (matched %v, %v), want a division by zero error", matched, err)
+	}
+}
+
+func TestScriptDecideStepBudget(t *testing.T) {
+	// ParseScript won't accept a script this big, so build it by hand as one
+	// could still turn up in a hand edited file
+	node := &scriptNode{op: "num", value: 0}
+	for i := 0; i < ScriptStepBudget; i++ {
+		node = &scriptNode{op: "+", args: []*scriptNode{node, {op: "num", value: 0}}}
+	}
+	script := &Script{rules: []scriptRule{{effort: node}}}
+	if _, _, _, err := script.decide(nil); err != errStepBudget {
+		t.Errorf("decide = %v, want %v", err, errStepBudget)
+	}
+}
+
+func TestScriptFallsBackOnStrategy(t *testing.T) {
+	players := []Player{{Name: "Hare", MinSpeed: 2, MaxSpeed: 2, Strategy: StrategyEven, Script: "when round > 100: rest"}}
+	race := NewRace(players, RaceConfig{TotalDistance: 100})
+	if rest, effort := race.decide(0); rest || effort != evenEffort {
+		t.Errorf("decide = (%v, %v), want the even pace effort %v", rest, effort, evenEffort)
+	}
+}
+
+func TestScriptRestShowsResting(t *testing.T) {
+	hare := steady("Hare", 1)
+	hare.Stamina, hare.FatigueRate, hare.RecoveryRate = 10, 1, 5
+	hare.Script = "when round == 2: rest"
+	race := NewRace([]Player{hare}, RaceConfig{TotalDistance: 100})
+
+	tests := []struct {
+		resting   bool
+		distance  float64
+		endurance float64
+	}{
+		{false, 1, 9},
+		{true, 1, 9},   // stops for the rest it chose
+		{false, 1, 10}, // then recovers, but only up to full
+		{false, 2, 9},
+	}
+	for round, test := range tests {
+		state := race.Step()
+		player := state.Players[0]
+		if player.Resting != test.resting || player.Distance != test.distance || player.Endurance != test.endurance {
+			t.Errorf("round %d: resting %v at %vm with %v endurance, want resting %v at %vm with %v",
+				round+1, player.Resting, player.Distance, player.Endurance, test.resting, test.distance, test.endurance)
+		}
+	}
+}
@@ -0,0 +1,337 @@
+package simulation
+//import some stuff
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"github.com/google/uuid"
+)
+
+// FixtureResult is how one animal did in a fixture
+type FixtureResult struct {
+	UUID   string
+	Place  int
+	Status string
+	Points float64
+}
+
+// Fixture is one heat in a season, Results and RaceUUID are filled in once it has been run
+type Fixture struct {
+	Matchday int      // fixtures on the same matchday have no animal in common
+	Entrants []string // animal UUIDs in lane order
+	Results  []FixtureResult
+	RaceUUID string // the saved race, so the fixture can be looked up or replayed
+}
+
+// Done reports whether the fixture has been run
+func (f Fixture) Done() bool {
+	return f.RaceUUID != ""
+}
+
+// Standing is one row of a season table
+type Standing struct {
+	UUID      string
+	Name      string
+	Races     int
+	Wins      int
+	Points    float64
+	BestPlace int
+}
+
+// Season is a league run over a fixed roster. The points animals earn are
+// kept in the season, apart from their all time score, and closing the season
+// archives its table.
+type Season struct {
+	UUID          string
+	Name          string
+	Created       string
+	Closed        string // date the season was closed, blank while it is running
+	TotalDistance int
+	Model         string
+	Scoring       ScoringScheme
+	Roster        []Entrant
+	Fixtures      []Fixture
+	Table         []Standing // the final table, only kept once the season is closed
+}
+
+// NewSeason makes a season and draws up its fixtures
+func NewSeason(name string, roster []Player, totalDistance int, model string, scoring ScoringScheme) (*Season, error) {
+	if name == "" {
+		return nil, fmt.Errorf("please enter a season name")
+	}
+	if len(roster) < 2 {
+		return nil, fmt.Errorf("a season needs at least two animals")
+	}
+	if totalDistance <= 0 {
+		return nil, fmt.Errorf("please enter a valid race length")
+	}
+	if _, err := NewMovementModel(model, 0); err != nil {
+		return nil, err
+	}
+
+	s := &Season{
+		UUID:          uuid.New().String(),
+		Name:          name,
+		Created:       time.Now().Format("2006-01-02 15:04:05"),
+		TotalDistance: totalDistance,
+		Model:         model,
+		Scoring:       scoring,
+	}
+	animals := make([]string, len(roster))
+	for i, animal := range roster {
+		s.Roster = append(s.Roster, Entrant{UUID: animal.UUID, Name: animal.Name, Seed: i + 1})
+		animals[i] = animal.UUID
+	}
+	s.Fixtures = roundRobinFixtures(animals)
+	return s, nil
+}
+
+// roundRobinFixtures draws up the matchdays with the circle method: one animal
+// stays put while the rest rotate, pairing the animals up differently every
+// matchday until every pair has met. Each matchday's pairs are grouped into
+// heats of up to HeatSize, so every animal races every other animal at least
+// once.
+func roundRobinFixtures(animals []string) []Fixture {
+	circle := make([]string, len(animals))
+	copy(circle, animals)
+	if len(circle)%2 == 1 {
+		circle = append(circle, "") // a bye, whoever is paired with it only races the other pair
+	}
+	n := len(circle)
+
+	var fixtures []Fixture
+	for matchday := 1; matchday < n; matchday++ {
+		var heats [][]string
+		var heat []string
+		for k := 0; k < n/2; k++ {
+			var pair []string
+			for _, animal := range []string{circle[k], circle[n-1-k]} {
+				if animal != "" {
+					pair = append(pair, animal)
+				}
+			}
+			if len(heat)+len(pair) > HeatSize {
+				heats = append(heats, heat)
+				heat = nil
+			}
+			heat = append(heat, pair...)
+		}
+		if len(heat) == 1 && len(heats) > 0 {
+			heats[len(heats)-1] = append(heats[len(heats)-1], heat[0]) // nobody to race so join the last heat
+		} else if len(heat) > 0 {
+			heats = append(heats, heat)
+		}
+		for _, entrants := range heats {
+			fixtures = append(fixtures, Fixture{Matchday: matchday, Entrants: entrants})
+		}
+
+		// rotate everyone but the first
+		last := circle[n-1]
+		copy(circle[2:], circle[1:n-1])
+		circle[1] = last
+	}
+	return fixtures
+}
+
+// EntrantName looks up an animal's name in the season
+func (s *Season) EntrantName(animalUUID string) string {
+	for _, entrant := range s.Roster {
+		if entrant.UUID == animalUUID {
+			return entrant.Name
+		}
+	}
+	return animalUUID
+}
+
+// IsClosed reports whether the season has been closed and archived
+func (s *Season) IsClosed() bool {
+	return s.Closed != ""
+}
+
+// NextFixture finds the first fixture still to be run, ok is false once they have all been run
+func (s *Season) NextFixture() (int, bool) {
+	for i, fixture := range s.Fixtures {
+		if !fixture.Done() {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// raceConfig is the config every fixture is run with, each fixture gets its
+// own seed. The tie policy and race speed are the league settings at the time.
+func (s *Season) raceConfig(tiePolicy string, tick time.Duration) RaceConfig {
+	return RaceConfig{TotalDistance: s.TotalDistance, Seed: NewSeed(), Model: s.Model, Scoring: s.Scoring, TiePolicy: tiePolicy, Tick: tick, Telemetry: true}
+}
+
+// finishFixture saves the fixture as a race and records the season points
+func (s *Season) finishFixture(i int, race *Race) error {
+	if s.Fixtures[i].Done() {
+		return fmt.Errorf("fixture %d has already been run", i+1) // two windows raced the same fixture
+	}
+	raceUUID := uuid.New().String()
+	SaveRaceResults(race, raceUUID)
+
+	fixture := &s.Fixtures[i]
+	fixture.Results = nil
+	for _, lane := range finishingOrder(race) {
+		player := race.Players[lane]
+		fixture.Results = append(fixture.Results, FixtureResult{UUID: player.UUID, Place: player.Place, Status: player.Status, Points: player.Score})
+	}
+	fixture.RaceUUID = raceUUID
+	return s.Save()
+}
+
+// RunFixture opens the race window for the next fixture, when the race is
+// over it is saved to the season and onDone is called
+func RunFixture(app fyne.App, s *Season, tiePolicy string, tick time.Duration, onDone func(error)) error {
+	if s.IsClosed() {
+		return fmt.Errorf("%s has been closed", s.Name)
+	}
+	i, ok := s.NextFixture()
+	if !ok {
+		return fmt.Errorf("every fixture in %s has been run", s.Name)
+	}
+	players, err := rosterPlayers(s.Fixtures[i].Entrants)
+	if err != nil {
+		return err
+	}
+
+	title := fmt.Sprintf("%s - matchday %d", s.Name, s.Fixtures[i].Matchday)
+	drawRaceTrack(app, title, len(players), 70, 1000, players, s.raceConfig(tiePolicy, tick), func(race *Race, _ fyne.Window) {
+		onDone(s.finishFixture(i, race))
+	})
+	return nil
+}
+
+// SimulateRemainingFixtures runs every fixture left without a window, a
+// fixture that is called off scores like one ended early with no points for
+// the DNFs
+func (s *Season) SimulateRemainingFixtures(tiePolicy string, tick time.Duration) error {
+	if s.IsClosed() {
+		return fmt.Errorf("%s has been closed", s.Name)
+	}
+	for {
+		i, ok := s.NextFixture()
+		if !ok {
+			return nil
+		}
+		players, err := rosterPlayers(s.Fixtures[i].Entrants)
+		if err != nil {
+			return err
+		}
+		race := NewRace(players, s.raceConfig(tiePolicy, tick))
+		race.Run()
+		CalculateScores(race)
+		if err := s.finishFixture(i, race); err != nil {
+			return err
+		}
+	}
+}
+
+// Standings is the season table from the fixtures run so far, most points
+// first with wins breaking ties. A closed season gives its archived table.
+func (s *Season) Standings() []Standing {
+	if s.IsClosed() {
+		return s.Table
+	}
+
+	rows := make(map[string]*Standing)
+	table := make([]Standing, 0, len(s.Roster))
+	for _, entrant := range s.Roster {
+		table = append(table, Standing{UUID: entrant.UUID, Name: entrant.Name})
+	}
+	for i := range table {
+		rows[table[i].UUID] = &table[i]
+	}
+	for _, fixture := range s.Fixtures {
+		for _, result := range fixture.Results {
+			row, ok := rows[result.UUID]
+			if !ok {
+				continue
+			}
+			row.Races++
+			row.Points += result.Points
+			if result.Status == StatusDNF {
+				continue
+			}
+			if result.Place == 1 {
+				row.Wins++
+			}
+			if result.Place > 0 && (row.BestPlace == 0 || result.Place < row.BestPlace) {
+				row.BestPlace = result.Place
+			}
+		}
+	}
+
+	sort.SliceStable(table, func(a, b int) bool {
+		if table[a].Points != table[b].Points {
+			return table[a].Points > table[b].Points
+		}
+		return table[a].Wins > table[b].Wins
+	})
+	return table
+}
+
+// Close ends the season and archives its table, fixtures not yet run are left unplayed
+func (s *Season) Close() error {
+	if s.IsClosed() {
+		return fmt.Errorf("%s is already closed", s.Name)
+	}
+	s.Table = s.Standings()
+	s.Closed = time.Now().Format("2006-01-02 15:04:05")
+	return s.Save()
+}
+
+// seasonPath is where a season is saved
+func seasonPath(seasonUUID string) string {
+	return filepath.Join("data", seasonUUID+".season")
+}
+
+// Save writes the season to data/<uuid>.season
+func (s *Season) Save() error {
+	file, err := os.Create(seasonPath(s.UUID))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(s)
+}
+
+// LoadSeasons reads every saved season, running seasons first then newest first
+func LoadSeasons() ([]*Season, error) {
+	paths, err := filepath.Glob(filepath.Join("data", "*.season"))
+	if err != nil {
+		return nil, err
+	}
+
+	var seasons []*Season
+	for _, path := range paths {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		var s Season
+		err = json.NewDecoder(file).Decode(&s)
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("invalid season file %s: %v", path, err)
+		}
+		seasons = append(seasons, &s)
+	}
+	sort.SliceStable(seasons, func(a, b int) bool {
+		if seasons[a].IsClosed() != seasons[b].IsClosed() {
+			return !seasons[a].IsClosed()
+		}
+		return seasons[a].Created > seasons[b].Created
+	})
+	return seasons, nil
+}
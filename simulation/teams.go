@@ -0,0 +1,140 @@
+package simulation
+//import some stuff
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// TeamsFile is where teams are kept, next to animal.simulation
+const TeamsFile = "data/teams.csv"
+
+// TeamHeader is the header row of the teams file
+var TeamHeader = []string{"Name", "UUID", "Members", "Score"}
+
+// Team is a group of animals that runs relays, Members are animal UUIDs in
+// the order they run their legs
+type Team struct {
+	Name    string
+	UUID    string
+	Members []string
+	Score   float64
+}
+
+// ReadTeams reads every team, no teams file just means no teams yet
+func ReadTeams(filename string) ([]Team, error) {
+	file, err := os.Open(filename)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	var teams []Team
+	for i, record := range records {
+		if i == 0 {
+			continue // Skip header row
+		}
+		if len(record) < len(TeamHeader) {
+			return nil, fmt.Errorf("malformed team on line %d of %s", i+1, filename)
+		}
+		score, _ := strconv.ParseFloat(record[3], 64)
+		teams = append(teams, Team{
+			Name:    record[0],
+			UUID:    record[1],
+			Members: strings.Split(record[2], ";"),
+			Score:   score,
+		})
+	}
+	return teams, nil
+}
+
+// WriteTeams overwrites the teams file with the given teams
+func WriteTeams(filename string, teams []Team) error {
+	data := [][]string{TeamHeader}
+	for _, team := range teams {
+		data = append(data, []string{
+			team.Name,
+			team.UUID,
+			strings.Join(team.Members, ";"),
+			strconv.FormatFloat(team.Score, 'f', -1, 64),
+		})
+	}
+	return WriteCSV(filename, data, false)
+}
+
+// CreateTeam adds a new team of the given animal UUIDs, in running order
+func CreateTeam(name string, members []string) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return fmt.Errorf("please enter a team name")
+	}
+	if len(members) < 2 {
+		return fmt.Errorf("a relay team needs at least two animals")
+	}
+
+	teams, err := ReadTeams(TeamsFile)
+	if err != nil {
+		return err
+	}
+	for _, team := range teams {
+		if team.Name == name {
+			return fmt.Errorf("there is already a team called %s", name)
+		}
+	}
+	teams = append(teams, Team{Name: name, UUID: uuid.New().String(), Members: members})
+	return WriteTeams(TeamsFile, teams)
+}
+
+// TeamPlayers turns teams into one lane each for a relay race, every lane
+// carries its team's animals from the roster as the legs it runs
+func TeamPlayers(teams []Team, roster []Player) ([]Player, error) {
+	animals := make(map[string]Player)
+	for _, animal := range roster {
+		animals[animal.UUID] = animal
+	}
+
+	var players []Player
+	for _, team := range teams {
+		lane := Player{Name: team.Name, UUID: team.UUID}
+		for _, member := range team.Members {
+			animal, ok := animals[member]
+			if !ok {
+				return nil, fmt.Errorf("team %s has an animal that is no longer in the roster", team.Name)
+			}
+			lane.Team = append(lane.Team, animal)
+		}
+		if len(lane.Team) == 0 {
+			return nil, fmt.Errorf("team %s has no animals", team.Name)
+		}
+		players = append(players, lane)
+	}
+	return players, nil
+}
+
+// SaveTeamScores adds each team's score from a relay to its total in the teams file
+func SaveTeamScores(filename string, players []Player) error {
+	teams, err := ReadTeams(filename)
+	if err != nil {
+		return err
+	}
+	scores := make(map[string]float64)
+	for _, player := range players {
+		scores[player.UUID] = player.Score
+	}
+	for i := range teams {
+		teams[i].Score += scores[teams[i].UUID]
+	}
+	return WriteTeams(filename, teams)
+}
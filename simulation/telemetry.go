@@ -0,0 +1,128 @@
+package simulation
+//import some stuff
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// telemetryHeader is the header row of a .telemetry file
+var telemetryHeader = []string{"Round", "UUID", "Name", "Distance", "Endurance", "Resting", "Run Distance", "Eliminated", "Leg", "Event"}
+
+// telemetryColumns is how many columns the oldest telemetry files have
+const telemetryColumns = 7
+
+// SaveTelemetry writes every recorded round to data/<uuid>.telemetry,
+// one row per animal per round so it can be loaded into a spreadsheet
+func SaveTelemetry(race *Race, uuid string) error {
+	if len(race.Telemetry) == 0 {
+		return nil // nothing was recorded for this race
+	}
+
+	file, err := os.Create(fmt.Sprintf("data/%s.telemetry", uuid))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	if err := writer.Write(telemetryHeader); err != nil {
+		return err
+	}
+
+	for _, round := range race.Telemetry {
+		for i, player := range round.Players {
+			record := []string{
+				strconv.Itoa(round.Round),
+				race.Players[i].UUID,
+				race.Players[i].Name,
+				strconv.FormatFloat(player.Distance, 'f', 3, 64),
+				strconv.FormatFloat(player.Endurance, 'f', 3, 64),
+				strconv.FormatBool(player.Resting),
+				strconv.FormatFloat(player.Run, 'f', 3, 64),
+				strconv.FormatBool(player.Eliminated),
+				strconv.Itoa(player.Leg),
+				player.Event,
+			}
+			if err := writer.Write(record); err != nil {
+				return err
+			}
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// LoadTelemetry reads data/<uuid>.telemetry back into rounds, the players
+// come back in lane order with just their name and UUID filled in
+func LoadTelemetry(uuid string) ([]Player, []RoundState, error) {
+	file, err := os.Open(fmt.Sprintf("data/%s.telemetry", uuid))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(records) < 2 {
+		return nil, nil, fmt.Errorf("no telemetry found for race %s", uuid)
+	}
+
+	var players []Player
+	var rounds []RoundState
+	lanes := make(map[string]int) // animal UUID to lane number
+	for i, record := range records[1:] {
+		if len(record) < telemetryColumns {
+			return nil, nil, fmt.Errorf("malformed telemetry row %d in race %s", i+1, uuid)
+		}
+		round, err := strconv.Atoi(record[0])
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid round in telemetry row %d: %v", i+1, err)
+		}
+
+		// the first round lists every lane, so that is where the players come from
+		lane, ok := lanes[record[1]]
+		if !ok {
+			lane = len(players)
+			lanes[record[1]] = lane
+			players = append(players, Player{UUID: record[1], Name: record[2]})
+		}
+
+		if len(rounds) == 0 || rounds[len(rounds)-1].Round != round {
+			rounds = append(rounds, RoundState{Round: round})
+		}
+		current := &rounds[len(rounds)-1]
+		for len(current.Players) <= lane {
+			current.Players = append(current.Players, PlayerState{})
+		}
+
+		distance, _ := strconv.ParseFloat(record[3], 64)
+		endurance, _ := strconv.ParseFloat(record[4], 64)
+		resting, _ := strconv.ParseBool(record[5])
+		run, _ := strconv.ParseFloat(record[6], 64)
+		eliminated := len(record) > 7 && record[7] == "true"
+		leg := 0
+		if len(record) > 8 {
+			leg, _ = strconv.Atoi(record[8])
+		}
+		event := ""
+		if len(record) > 9 {
+			event = record[9]
+		}
+		current.Players[lane] = PlayerState{
+			Distance:   distance,
+			Endurance:  endurance,
+			Resting:    resting,
+			Run:        run,
+			Eliminated: eliminated,
+			Leg:        leg,
+			Event:      event,
+		}
+	}
+
+	return players, rounds, nil
+}
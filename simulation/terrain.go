@@ -0,0 +1,245 @@
+package simulation
+//import some stuff
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// terrain a track segment can be made of
+const (
+	TerrainFlat     = "Flat"
+	TerrainUphill   = "Uphill"
+	TerrainDownhill = "Downhill"
+	TerrainMud      = "Mud"
+	TerrainSand     = "Sand"
+	TerrainWater    = "Water"
+)
+
+// TerrainTypes lists the terrain for the track builder
+var TerrainTypes = []string{TerrainFlat, TerrainUphill, TerrainDownhill, TerrainMud, TerrainSand, TerrainWater}
+
+// terrainDefaults are the speed and endurance multipliers a new segment of each terrain starts with
+var terrainDefaults = map[string][2]float64{
+	TerrainFlat:     {1, 1},
+	TerrainUphill:   {0.7, 1.6},
+	TerrainDownhill: {1.25, 0.8},
+	TerrainMud:      {0.6, 1.4},
+	TerrainSand:     {0.75, 1.3},
+	TerrainWater:    {0.5, 1.8},
+}
+
+// terrainLimits are the largest speed and endurance multipliers of the
+// built-in terrain. Segments are held to them so a custom track can't tire
+// an animal out more than ValidateEndurance allows for.
+func terrainLimits() (speed float64, endurance float64) {
+	for _, defaults := range terrainDefaults {
+		speed = math.Max(speed, defaults[0])
+		endurance = math.Max(endurance, defaults[1])
+	}
+	return speed, endurance
+}
+
+// FlatTrack is the name shown for a race without a track, one flat lane like the original races
+const FlatTrack = "Flat"
+
+// TracksFile is the library of saved tracks
+const TracksFile = "data/tracks.json"
+
+// Segment is one stretch of a track. Lengths are shares of the track so the
+// same track fits any race length, segments of 1, 2 and 1 on a 400m race are
+// 100m, 200m and 100m.
+type Segment struct {
+	Terrain   string
+	Length    float64
+	Speed     float64 // multiplies how far an animal runs each round on the segment
+	Endurance float64 // multiplies the endurance running costs on the segment
+}
+
+// NewSegment makes a segment of the given terrain with its default multipliers
+func NewSegment(terrain string, length float64) (Segment, error) {
+	defaults, ok := terrainDefaults[terrain]
+	if !ok {
+		return Segment{}, fmt.Errorf("unknown terrain %q", terrain)
+	}
+	return Segment{Terrain: terrain, Length: length, Speed: defaults[0], Endurance: defaults[1]}, nil
+}
+
+// flatSegment is what animals run on when the race has no track
+var flatSegment = Segment{Terrain: TerrainFlat, Length: 1, Speed: 1, Endurance: 1}
+
+// Track is a named course made of segments, the zero value is a flat track
+type Track struct {
+	Name     string
+	Segments []Segment
+}
+
+// IsFlat reports whether the track is the plain flat lane
+func (t Track) IsFlat() bool {
+	return len(t.Segments) == 0
+}
+
+// DisplayName is the track's name, FlatTrack for the plain flat lane
+func (t Track) DisplayName() string {
+	if t.IsFlat() {
+		return FlatTrack
+	}
+	return t.Name
+}
+
+// Validate checks the track has a name and usable segments
+func (t Track) Validate() error {
+	if t.Name == "" {
+		return fmt.Errorf("please enter a track name")
+	}
+	if t.Name == FlatTrack {
+		return fmt.Errorf("%q is the name of the plain track, please pick another", FlatTrack)
+	}
+	if len(t.Segments) == 0 {
+		return fmt.Errorf("a track needs at least one segment")
+	}
+	maxSpeed, maxEndurance := terrainLimits()
+	for i, segment := range t.Segments {
+		if _, ok := terrainDefaults[segment.Terrain]; !ok {
+			return fmt.Errorf("segment %d: unknown terrain %q", i+1, segment.Terrain)
+		}
+		if segment.Length <= 0 {
+			return fmt.Errorf("segment %d: length must be above 0", i+1)
+		}
+		if segment.Speed <= 0 || segment.Endurance <= 0 {
+			return fmt.Errorf("segment %d: multipliers must be above 0", i+1)
+		}
+		if segment.Speed > maxSpeed || segment.Endurance > maxEndurance {
+			return fmt.Errorf("segment %d: speed can be at most %v and endurance at most %v", i+1, maxSpeed, maxEndurance)
+		}
+		// the speed scales how far a round goes and so what it costs too
+		if segment.Speed*segment.Endurance > maxEndurance {
+			return fmt.Errorf("segment %d: speed times endurance can be at most %v, or animals could tire out for good", i+1, maxEndurance)
+		}
+	}
+	return nil
+}
+
+// totalLength is the sum of the segment lengths
+func (t Track) totalLength() float64 {
+	total := 0.0
+	for _, segment := range t.Segments {
+		total += segment.Length
+	}
+	return total
+}
+
+// SegmentBounds is where each segment starts and ends on a track of the given length
+func (t Track) SegmentBounds(trackLength float64) [][2]float64 {
+	bounds := make([][2]float64, len(t.Segments))
+	total := t.totalLength()
+	start := 0.0
+	for i, segment := range t.Segments {
+		end := start + segment.Length/total*trackLength
+		bounds[i] = [2]float64{start, end}
+		start = end
+	}
+	return bounds
+}
+
+// SegmentAt is the segment at the given distance along a track of the given
+// length, anything past the end is on the last segment
+func (t Track) SegmentAt(distance, trackLength float64) Segment {
+	if t.IsFlat() {
+		return flatSegment
+	}
+	for i, bound := range t.SegmentBounds(trackLength) {
+		if distance < bound[1] {
+			return t.Segments[i]
+		}
+	}
+	return t.Segments[len(t.Segments)-1]
+}
+
+// FormatSegments writes the segments for the race file as terrain:length:speed:endurance separated by ;
+func FormatSegments(segments []Segment) string {
+	parts := make([]string, len(segments))
+	for i, segment := range segments {
+		parts[i] = fmt.Sprintf("%s:%s:%s:%s", segment.Terrain,
+			strconv.FormatFloat(segment.Length, 'f', -1, 64),
+			strconv.FormatFloat(segment.Speed, 'f', -1, 64),
+			strconv.FormatFloat(segment.Endurance, 'f', -1, 64))
+	}
+	return strings.Join(parts, ";")
+}
+
+// ParseSegments reads segments written by FormatSegments, blank is a flat track
+func ParseSegments(text string) ([]Segment, error) {
+	if text == "" {
+		return nil, nil
+	}
+	var segments []Segment
+	for _, part := range strings.Split(text, ";") {
+		fields := strings.Split(part, ":")
+		if len(fields) != 4 {
+			return nil, fmt.Errorf("invalid track segment %q", part)
+		}
+		segment := Segment{Terrain: fields[0]}
+		var err error
+		for k, value := range []*float64{&segment.Length, &segment.Speed, &segment.Endurance} {
+			*value, err = strconv.ParseFloat(fields[k+1], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid track segment %q: %v", part, err)
+			}
+		}
+		segments = append(segments, segment)
+	}
+	return segments, nil
+}
+
+// ReadTracks reads the track library, a missing file just means no tracks have been made yet
+func ReadTracks(filename string) ([]Track, error) {
+	file, err := os.Open(filename)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var tracks []Track
+	if err := json.NewDecoder(file).Decode(&tracks); err != nil {
+		return nil, fmt.Errorf("invalid track library %s: %v", filename, err)
+	}
+	return tracks, nil
+}
+
+// WriteTracks overwrites the track library
+func WriteTracks(filename string, tracks []Track) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(tracks)
+}
+
+// SaveTrack validates a track and adds it to the library, replacing any track with the same name
+func SaveTrack(filename string, track Track) error {
+	if err := track.Validate(); err != nil {
+		return err
+	}
+	tracks, err := ReadTracks(filename)
+	if err != nil {
+		return err
+	}
+	for i := range tracks {
+		if tracks[i].Name == track.Name {
+			tracks[i] = track
+			return WriteTracks(filename, tracks)
+		}
+	}
+	return WriteTracks(filename, append(tracks, track))
+}
@@ -0,0 +1,355 @@
+package simulation
+//import some stuff
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"github.com/google/uuid"
+)
+
+// how a tournament's entrants are spread over the first heats
+const (
+	SeedingScore  = "Leaderboard Score" // best scores are kept apart until the later rounds
+	SeedingRandom = "Random"
+)
+
+// TournamentSeedings lists the seedings for the new tournament menu
+var TournamentSeedings = []string{SeedingScore, SeedingRandom}
+
+// TournamentSizes are the bracket sizes on offer
+var TournamentSizes = []int{8, 16, 32}
+
+// every heat has HeatSize animals and the top HeatQualifiers go through,
+// once there are only HeatSize left they race the final
+const (
+	HeatSize       = 4
+	HeatQualifiers = 2
+)
+
+// Entrant is an animal in a tournament, Seed is 1 for the top seed
+type Entrant struct {
+	UUID string
+	Name string
+	Seed int
+}
+
+// Heat is one race in a tournament, Finishers and RaceUUID are filled in once it has been run
+type Heat struct {
+	Entrants  []string // animal UUIDs in lane order
+	Finishers []string // animal UUIDs in finishing order
+	RaceUUID  string   // the saved race, so the heat can be looked up or replayed
+}
+
+// Done reports whether the heat has been run
+func (h Heat) Done() bool {
+	return h.RaceUUID != ""
+}
+
+// TournamentRound is every heat at one stage of the bracket
+type TournamentRound struct {
+	Name  string
+	Heats []Heat
+}
+
+// Tournament is a knockout bracket, it is saved after every heat so it can be
+// picked up again later
+type Tournament struct {
+	UUID          string
+	Name          string
+	Created       string
+	Seeding       string
+	TotalDistance int
+	Model         string
+	Entrants      []Entrant
+	Rounds        []TournamentRound
+	Winner        string // UUID of the winner once the final has been run
+}
+
+// NewTournament seeds the animals into the first round's heats, there must
+// be 8, 16 or 32 of them
+func NewTournament(name string, animals []Player, seeding string, totalDistance int, model string) (*Tournament, error) {
+	validSize := false
+	for _, size := range TournamentSizes {
+		validSize = validSize || len(animals) == size
+	}
+	if !validSize {
+		return nil, fmt.Errorf("a tournament needs 8, 16 or 32 animals, %d were picked", len(animals))
+	}
+	if name == "" {
+		return nil, fmt.Errorf("please enter a tournament name")
+	}
+	if totalDistance <= 0 {
+		return nil, fmt.Errorf("please enter a valid race length")
+	}
+	if _, err := NewMovementModel(model, 0); err != nil {
+		return nil, err
+	}
+
+	seeded := make([]Player, len(animals))
+	copy(seeded, animals)
+	switch seeding {
+	case SeedingScore:
+		sort.SliceStable(seeded, func(a, b int) bool {
+			return seeded[a].Score > seeded[b].Score
+		})
+	case SeedingRandom:
+		rng := rand.New(rand.NewSource(NewSeed()))
+		rng.Shuffle(len(seeded), func(a, b int) {
+			seeded[a], seeded[b] = seeded[b], seeded[a]
+		})
+	default:
+		return nil, fmt.Errorf("unknown seeding %q", seeding)
+	}
+
+	t := &Tournament{
+		UUID:          uuid.New().String(),
+		Name:          name,
+		Created:       time.Now().Format("2006-01-02 15:04:05"),
+		Seeding:       seeding,
+		TotalDistance: totalDistance,
+		Model:         model,
+	}
+	seeds := make([]string, len(seeded))
+	for i, animal := range seeded {
+		t.Entrants = append(t.Entrants, Entrant{UUID: animal.UUID, Name: animal.Name, Seed: i + 1})
+		seeds[i] = animal.UUID
+	}
+	t.addRound(seeds)
+	return t, nil
+}
+
+// addRound spreads the animals, best first, over the next round's heats in a
+// snake so the top seeds each lead a different heat
+func (t *Tournament) addRound(animals []string) {
+	heats := make([]Heat, len(animals)/HeatSize)
+	for k, animal := range animals {
+		row, column := k/len(heats), k%len(heats)
+		if row%2 == 1 {
+			column = len(heats) - 1 - column
+		}
+		heats[column].Entrants = append(heats[column].Entrants, animal)
+	}
+	t.Rounds = append(t.Rounds, TournamentRound{Name: roundName(len(heats), len(t.Rounds)), Heats: heats})
+}
+
+// roundName names a stage of the bracket by how many heats it has
+func roundName(heats, round int) string {
+	switch heats {
+	case 1:
+		return "Final"
+	case 2:
+		return "Semi Finals"
+	}
+	return fmt.Sprintf("Round %d", round+1)
+}
+
+// EntrantName looks up an animal's name in the tournament
+func (t *Tournament) EntrantName(animalUUID string) string {
+	for _, entrant := range t.Entrants {
+		if entrant.UUID == animalUUID {
+			return entrant.Name
+		}
+	}
+	return animalUUID
+}
+
+// Finished reports whether the final has been run
+func (t *Tournament) Finished() bool {
+	return t.Winner != ""
+}
+
+// NextHeat finds the first heat still to be run, ok is false once the tournament is over
+func (t *Tournament) NextHeat() (round, heat int, ok bool) {
+	for r, stage := range t.Rounds {
+		for h, next := range stage.Heats {
+			if !next.Done() {
+				return r, h, true
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+// heatPlayers gets the heat's animals from the roster as they are now
+func (t *Tournament) heatPlayers(round, heat int) ([]Player, error) {
+	return rosterPlayers(t.Rounds[round].Heats[heat].Entrants)
+}
+
+// rosterPlayers gets animals from the roster as they are now, in the order given
+func rosterPlayers(animalUUIDs []string) ([]Player, error) {
+	roster, err := ReadCSV("data/animal.simulation")
+	if err != nil {
+		return nil, err
+	}
+	animals := make(map[string]Player)
+	for _, animal := range roster {
+		animals[animal.UUID] = animal
+	}
+
+	var players []Player
+	for _, animalUUID := range animalUUIDs {
+		animal, ok := animals[animalUUID]
+		if !ok {
+			return nil, fmt.Errorf("animal %s is no longer in the roster", animalUUID)
+		}
+		players = append(players, animal)
+	}
+	return players, nil
+}
+
+// finishingOrder lists the race's lanes by place
+func finishingOrder(race *Race) []int {
+	order := make([]int, len(race.Players))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return race.Players[order[a]].Place < race.Players[order[b]].Place
+	})
+	return order
+}
+
+// raceConfig is the config every heat is run with, each heat gets its own
+// seed. The tie policy and race speed are the league settings at the time.
+func (t *Tournament) raceConfig(tiePolicy string, tick time.Duration) RaceConfig {
+	return RaceConfig{TotalDistance: t.TotalDistance, Seed: NewSeed(), Model: t.Model, TiePolicy: tiePolicy, Tick: tick, Telemetry: true}
+}
+
+// finishHeat saves the heat as a race, records who got through and starts
+// the next round once every heat in this one has been run
+func (t *Tournament) finishHeat(round, heat int, race *Race) error {
+	if t.Rounds[round].Heats[heat].Done() {
+		return fmt.Errorf("%s heat %d has already been run", t.Rounds[round].Name, heat+1) // two windows raced the same heat
+	}
+	raceUUID := uuid.New().String()
+	SaveRaceResults(race, raceUUID)
+
+	current := &t.Rounds[round].Heats[heat]
+	current.Finishers = nil
+	for _, i := range finishingOrder(race) {
+		current.Finishers = append(current.Finishers, race.Players[i].UUID)
+	}
+	current.RaceUUID = raceUUID
+
+	if round == len(t.Rounds)-1 {
+		t.advance()
+	}
+	return t.Save()
+}
+
+// advance starts the next round once the last one is complete, heat winners
+// are seeded ahead of the runners up. After the final it records the winner.
+func (t *Tournament) advance() {
+	last := t.Rounds[len(t.Rounds)-1]
+	for _, heat := range last.Heats {
+		if !heat.Done() {
+			return
+		}
+	}
+	if len(last.Heats) == 1 {
+		t.Winner = last.Heats[0].Finishers[0]
+		return
+	}
+
+	var qualifiers []string
+	for place := 0; place < HeatQualifiers; place++ {
+		for _, heat := range last.Heats {
+			if place < len(heat.Finishers) {
+				qualifiers = append(qualifiers, heat.Finishers[place])
+			}
+		}
+	}
+	t.addRound(qualifiers)
+}
+
+// RunHeat opens the race window for the next heat, when the race is over it
+// is saved and the tournament moves on, then onDone is called
+func RunHeat(app fyne.App, t *Tournament, tiePolicy string, tick time.Duration, onDone func(error)) error {
+	round, heat, ok := t.NextHeat()
+	if !ok {
+		return fmt.Errorf("%s is already over", t.Name)
+	}
+	players, err := t.heatPlayers(round, heat)
+	if err != nil {
+		return err
+	}
+
+	title := fmt.Sprintf("%s - %s heat %d", t.Name, t.Rounds[round].Name, heat+1)
+	drawRaceTrack(app, title, len(players), 70, 1000, players, t.raceConfig(tiePolicy, tick), func(race *Race, _ fyne.Window) {
+		onDone(t.finishHeat(round, heat, race))
+	})
+	return nil
+}
+
+// SimulateRemainingHeats runs every heat left without a window, a heat that
+// is called off goes through like one ended early, the animals still running
+// placed behind the finishers by distance as DNFs
+func (t *Tournament) SimulateRemainingHeats(tiePolicy string, tick time.Duration) error {
+	for {
+		round, heat, ok := t.NextHeat()
+		if !ok {
+			return nil
+		}
+		players, err := t.heatPlayers(round, heat)
+		if err != nil {
+			return err
+		}
+		race := NewRace(players, t.raceConfig(tiePolicy, tick))
+		race.Run()
+		CalculateScores(race)
+		if err := t.finishHeat(round, heat, race); err != nil {
+			return err
+		}
+	}
+}
+
+// tournamentPath is where a tournament is saved
+func tournamentPath(tournamentUUID string) string {
+	return filepath.Join("data", tournamentUUID+".tournament")
+}
+
+// Save writes the tournament to data/<uuid>.tournament
+func (t *Tournament) Save() error {
+	file, err := os.Create(tournamentPath(t.UUID))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(t)
+}
+
+// LoadTournaments reads every saved tournament, newest first
+func LoadTournaments() ([]*Tournament, error) {
+	paths, err := filepath.Glob(filepath.Join("data", "*.tournament"))
+	if err != nil {
+		return nil, err
+	}
+
+	var tournaments []*Tournament
+	for _, path := range paths {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		var t Tournament
+		err = json.NewDecoder(file).Decode(&t)
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("invalid tournament file %s: %v", path, err)
+		}
+		tournaments = append(tournaments, &t)
+	}
+	sort.SliceStable(tournaments, func(a, b int) bool {
+		return tournaments[a].Created > tournaments[b].Created
+	})
+	return tournaments, nil
+}
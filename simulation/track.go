@@ -0,0 +1,260 @@
+package simulation
+//import some stuff
+import (
+	"fmt"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"image/color"
+	"os"
+)
+
+// raceTrack is the lane layout shared by the live race window and the replay window
+type raceTrack struct {
+	content       *fyne.Container
+	lanes         [][]*canvas.Rectangle // each lane is a strip per track segment
+	terrain       Track
+	images        []*canvas.Image
+	legImages     [][]string // image file for each leg of a relay lane
+	progressTexts []*canvas.Text
+	captions      []*canvas.Text // pops up over the animal when a random event hits it
+	laneHeight    int
+	windowWidth   float32
+	totalDistance int
+}
+
+// lane colours, alternating greens and grey for a knocked out animal
+var (
+	lightGreen     = color.RGBA{34, 139, 34, 255}
+	darkGreen      = color.RGBA{0, 100, 0, 255}
+	eliminatedGrey = color.RGBA{110, 110, 110, 255}
+)
+
+// terrainColors are the lane colours for each terrain off the flat
+var terrainColors = map[string]color.RGBA{
+	TerrainUphill:   {139, 115, 60, 255},
+	TerrainDownhill: {120, 170, 60, 255},
+	TerrainMud:      {95, 65, 35, 255},
+	TerrainSand:     {210, 180, 120, 255},
+	TerrainWater:    {40, 110, 190, 255},
+}
+
+// laneColor alternates the greens so neighbouring lanes stand apart
+func laneColor(lane int) color.Color {
+	if lane%2 == 1 {
+		return darkGreen
+	}
+	return lightGreen
+}
+
+// terrainColor is the colour of a segment in a lane, odd lanes a shade darker like the greens
+func terrainColor(terrain string, lane int) color.Color {
+	c, ok := terrainColors[terrain]
+	if !ok {
+		return laneColor(lane)
+	}
+	if lane%2 == 1 {
+		c.R, c.G, c.B = c.R*4/5, c.G*4/5, c.B*4/5
+	}
+	return c
+}
+
+// newRaceTrack draws one lane per player with their name, distance and image,
+// the lanes are coloured by the terrain along the track
+func newRaceTrack(players []Player, laneHeight int, windowWidth float32, totalDistance int, terrain Track) *raceTrack {
+	track := &raceTrack{
+		content:       container.NewWithoutLayout(),
+		lanes:         make([][]*canvas.Rectangle, len(players)),
+		terrain:       terrain,
+		images:        make([]*canvas.Image, len(players)),
+		legImages:     make([][]string, len(players)),
+		progressTexts: make([]*canvas.Text, len(players)),
+		captions:      make([]*canvas.Text, len(players)),
+		laneHeight:    laneHeight,
+		windowWidth:   windowWidth,
+		totalDistance: totalDistance,
+	}
+
+	for i := range players {
+		for _, strip := range track.segmentStrips(i) {
+			lane := canvas.NewRectangle(terrainColor(strip.terrain, i))
+			lane.Resize(fyne.NewSize(strip.end-strip.start, float32(laneHeight)))
+			lane.Move(fyne.NewPos(strip.start, float32(laneHeight)*float32(i)))
+			track.lanes[i] = append(track.lanes[i], lane)
+			track.content.Add(lane)
+		}
+
+		// Display player names and distance travelled at the beginning of lanes
+		playerNameText := canvas.NewText(players[i].Name, theme.ForegroundColor())
+		playerNameText.TextSize = 18
+		playerNameText.Move(fyne.NewPos(10, float32(laneHeight*i)+5))
+		track.content.Add(playerNameText)
+
+		// Distance text
+		progressText := canvas.NewText(fmt.Sprintf("%.1f/%d", players[i].Handicap, totalDistance), theme.ForegroundColor())
+		progressText.TextSize = 18
+		progressText.Move(fyne.NewPos(150, float32(laneHeight*i)+5))
+		track.progressTexts[i] = progressText
+		track.content.Add(progressText)
+	}
+
+	// name each stretch of terrain along the bottom of the track
+	if !terrain.IsFlat() && len(players) > 0 {
+		for _, strip := range track.segmentStrips(len(players) - 1) {
+			label := canvas.NewText(strip.terrain, color.White)
+			label.TextSize = 12
+			label.Move(fyne.NewPos(strip.start+4, float32(laneHeight*len(players))-16))
+			track.content.Add(label)
+		}
+	}
+
+	// mark where each relay leg hands over
+	for i := range players {
+		for leg := 1; leg < len(players[i].Team); leg++ {
+			distance := float64(totalDistance) * float64(leg) / float64(len(players[i].Team))
+			x := track.position(i, distance).X + 25
+			changeover := canvas.NewLine(color.RGBA{255, 255, 255, 120})
+			changeover.Position1 = fyne.NewPos(x, float32(laneHeight*i))
+			changeover.Position2 = fyne.NewPos(x, float32(laneHeight*(i+1)))
+			track.content.Add(changeover)
+		}
+	}
+
+	// mark where handicapped animals start so the staggered start shows
+	for i := range players {
+		if players[i].Handicap == 0 {
+			continue
+		}
+		x := track.position(i, players[i].Handicap).X + 25
+		startLine := canvas.NewLine(color.White)
+		startLine.StrokeWidth = 2
+		startLine.Position1 = fyne.NewPos(x, float32(laneHeight*i))
+		startLine.Position2 = fyne.NewPos(x, float32(laneHeight*(i+1)))
+		track.content.Add(startLine)
+	}
+
+	for i := range players {
+		// a relay lane shows whoever is running the current leg
+		imagePath := animalImage(players[i])
+		for _, member := range players[i].Team {
+			track.legImages[i] = append(track.legImages[i], animalImage(member))
+		}
+		if len(track.legImages[i]) > 0 {
+			imagePath = track.legImages[i][0]
+		}
+
+		animal := canvas.NewImageFromFile(imagePath)
+		animal.Resize(fyne.NewSize(50, 50))
+		animal.Move(track.position(i, players[i].Handicap))
+		track.images[i] = animal
+		track.content.Add(animal)
+
+		caption := canvas.NewText("", color.RGBA{255, 235, 60, 255})
+		caption.TextSize = 14
+		caption.TextStyle = fyne.TextStyle{Bold: true}
+		track.captions[i] = caption
+		track.content.Add(caption)
+	}
+
+	return track
+}
+
+// animalImage finds the picture for an animal, default.png if it hasn't got one
+func animalImage(player Player) string {
+	imagePath := fmt.Sprintf("data/%s.png", player.UUID)
+
+	// Check if the image exists, if not use default.png
+	if _, err := os.Stat(imagePath); os.IsNotExist(err) {
+		fmt.Printf("Image for %s not found, using default.png\n", player.Name)
+		return "data/default.png"
+	}
+	fmt.Printf("Using image for %s: %s\n", player.Name, imagePath)
+	return imagePath
+}
+
+// segmentStrip is where a track segment is drawn across a lane
+type segmentStrip struct {
+	terrain    string
+	start, end float32
+}
+
+// segmentStrips splits a lane into the stretches of terrain, lined up with
+// the middle of the animal image so it changes colour as it reaches a segment
+func (t *raceTrack) segmentStrips(lane int) []segmentStrip {
+	if t.terrain.IsFlat() {
+		return []segmentStrip{{terrain: TerrainFlat, start: 0, end: t.windowWidth}}
+	}
+	var strips []segmentStrip
+	for k, bound := range t.terrain.SegmentBounds(float64(t.totalDistance)) {
+		strip := segmentStrip{
+			terrain: t.terrain.Segments[k].Terrain,
+			start:   t.position(lane, bound[0]).X + 25,
+			end:     t.position(lane, bound[1]).X + 25,
+		}
+		if k == 0 {
+			strip.start = 0
+		}
+		if k == len(t.terrain.Segments)-1 {
+			strip.end = t.windowWidth
+		}
+		strips = append(strips, strip)
+	}
+	return strips
+}
+
+// height is how tall all the lanes are together
+func (t *raceTrack) height() float32 {
+	return float32(len(t.images)) * float32(t.laneHeight)
+}
+
+// position works out where an animal image goes for the distance it has covered
+func (t *raceTrack) position(lane int, distance float64) fyne.Position {
+	playerProgress := (distance / float64(t.totalDistance)) * float64(t.windowWidth-50)
+	if playerProgress > float64(t.windowWidth-50) {
+		playerProgress = float64(t.windowWidth - 50)
+	}
+	return fyne.NewPos(float32(playerProgress), float32(t.laneHeight*lane+t.laneHeight/2)-25)
+}
+
+// renderPlayer moves one animal and updates its distance text, animals
+// knocked out of an elimination race have their lane greyed out
+func (t *raceTrack) renderPlayer(lane int, player PlayerState) {
+	t.images[lane].Move(t.position(lane, player.Distance))
+	if legs := t.legImages[lane]; player.Leg < len(legs) && t.images[lane].File != legs[player.Leg] {
+		t.images[lane].File = legs[player.Leg] // the baton has been handed over
+	}
+	t.images[lane].Translucency = 0
+	if player.Eliminated {
+		t.images[lane].Translucency = 0.6
+	}
+	for k, strip := range t.segmentStrips(lane) {
+		t.lanes[lane][k].FillColor = terrainColor(strip.terrain, lane)
+		if player.Eliminated {
+			t.lanes[lane][k].FillColor = eliminatedGrey
+		}
+		canvas.Refresh(t.lanes[lane][k])
+	}
+	canvas.Refresh(t.images[lane])
+
+	t.progressTexts[lane].Text = fmt.Sprintf("%.1f/%d", player.Distance, t.totalDistance)
+	canvas.Refresh(t.progressTexts[lane])
+
+	// the caption sits beside the animal for the round the event happens in,
+	// on its left once it is too close to the end of the lane
+	position := t.position(lane, player.Distance)
+	t.captions[lane].Text = EventCaption(player.Event)
+	x := position.X + 55
+	if x+t.captions[lane].MinSize().Width > t.windowWidth {
+		x = position.X - t.captions[lane].MinSize().Width - 5
+	}
+	t.captions[lane].Move(fyne.NewPos(x, position.Y))
+	canvas.Refresh(t.captions[lane])
+}
+
+// render draws every lane as it was in the given round
+func (t *raceTrack) render(state RoundState) {
+	for i, player := range state.Players {
+		t.renderPlayer(i, player)
+	}
+}
@@ -0,0 +1,117 @@
+package simulation
+//import some stuff
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+// weather a race can be run in
+const (
+	WeatherSunny = "Sunny"
+	WeatherRain  = "Rain"
+	WeatherWind  = "Wind"
+	WeatherHeat  = "Heat"
+)
+
+// Weathers lists the weather for the race setup menu
+var Weathers = []string{WeatherSunny, WeatherRain, WeatherWind, WeatherHeat}
+
+// WeatherRandom is the setup option that picks the weather for the user
+const WeatherRandom = "Random"
+
+// weatherEffect is how the weather changes every animal's running
+type weatherEffect struct {
+	Speed     float64 // multiplies how far an animal runs each round
+	Endurance float64 // multiplies the endurance running costs
+	Gusts     float64 // the speed swings up or down by up to this share each round
+}
+
+// weatherEffects for each weather, sunny leaves races as they always were
+var weatherEffects = map[string]weatherEffect{
+	WeatherSunny: {Speed: 1, Endurance: 1},
+	WeatherRain:  {Speed: 0.9, Endurance: 1.1},
+	WeatherWind:  {Speed: 0.95, Endurance: 1.15, Gusts: 0.15},
+	WeatherHeat:  {Speed: 0.95, Endurance: 1.4},
+}
+
+// RandomWeather picks the weather for a race from its seed, so the same seed gets the same weather
+func RandomWeather(seed int64) string {
+	rng := rand.New(rand.NewSource(seed))
+	return Weathers[rng.Intn(len(Weathers))]
+}
+
+// weatherSpeed is how the weather changes player i's run this round, their
+// affinity for it on top of the weather itself
+func (r *Race) weatherSpeed(i int) float64 {
+	effect := weatherEffects[r.Weather]
+	speed := effect.Speed * r.Players[i].Affinity(r.Weather)
+	if effect.Gusts > 0 {
+		speed *= 1 + RandomFloat(r.rng, -effect.Gusts, effect.Gusts)
+	}
+	return speed
+}
+
+// weatherDrain is how much more tiring running is in the race's weather for
+// player i. Affinity takes the same effort, so an animal that likes the
+// weather covers more ground for its endurance rather than tiring sooner.
+func (r *Race) weatherDrain(i int) float64 {
+	return weatherEffects[r.Weather].Endurance / r.Players[i].Affinity(r.Weather)
+}
+
+// Affinity is how much further, or less far below 1, the animal gets for the same effort in the given weather
+func (p Player) Affinity(weather string) float64 {
+	if affinity, ok := p.Affinities[weather]; ok {
+		return affinity
+	}
+	return 1
+}
+
+// FormatAffinities writes an animal's weather affinities as weather:multiplier
+// separated by ;, in the order of Weathers
+func FormatAffinities(affinities map[string]float64) string {
+	var parts []string
+	for _, weather := range Weathers {
+		if affinity, ok := affinities[weather]; ok {
+			parts = append(parts, weather+":"+strconv.FormatFloat(affinity, 'f', -1, 64))
+		}
+	}
+	return strings.Join(parts, ";")
+}
+
+// ParseAffinities reads affinities written by FormatAffinities, blank means
+// the animal doesn't mind the weather
+func ParseAffinities(text string) (map[string]float64, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, nil
+	}
+	affinities := make(map[string]float64)
+	for _, part := range strings.Split(text, ";") {
+		fields := strings.Split(strings.TrimSpace(part), ":")
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("invalid weather affinity %q, use weather:multiplier like Rain:1.2", part)
+		}
+		weather := strings.TrimSpace(fields[0])
+		if _, ok := weatherEffects[weather]; !ok {
+			return nil, fmt.Errorf("unknown weather %q in affinity %q", weather, part)
+		}
+		affinity, err := strconv.ParseFloat(strings.TrimSpace(fields[1]), 64)
+		if err != nil || affinity <= 0 {
+			return nil, fmt.Errorf("invalid weather affinity %q, the multiplier must be above 0", part)
+		}
+		affinities[weather] = affinity
+	}
+	return affinities, nil
+}
+
+// recordAffinities reads the weather affinity column of an animal record,
+// older records and anything that won't parse get no affinities
+func recordAffinities(record []string) map[string]float64 {
+	if len(record) <= 8 {
+		return nil
+	}
+	affinities, _ := ParseAffinities(record[8])
+	return affinities
+}
@@ -48,8 +48,36 @@ func AddAnimal(hareandtortoise fyne.App, window fyne.Window) {
 	animalMinSpeed.SetPlaceHolder("Minimum speed")
 	animalMaxSpeed := newNumericalEntry()
 	animalMaxSpeed.SetPlaceHolder("Maximum speed")
+	animalStamina := newNumericalEntry()
+	animalStamina.SetPlaceHolder("Stamina (default 100)")
+	animalFatigueRate := newNumericalEntry()
+	animalFatigueRate.SetPlaceHolder("Fatigue rate (default 1)")
+	animalRecoveryRate := newNumericalEntry()
+	animalRecoveryRate.SetPlaceHolder("Recovery rate (default 3x min speed)")
+	animalAffinities := widget.NewEntry()
+	animalAffinities.SetPlaceHolder("Weather affinity, e.g. Rain:1.2;Heat:0.8")
+	animalEventChances := widget.NewEntry()
+	animalEventChances.SetPlaceHolder("Event chances, e.g. Nap:0.5 (blank for defaults)")
+	animalStrategy := widget.NewSelect(simulation.Strategies, nil)
+	animalStrategy.SetSelected(simulation.StrategyFlatOut)
+	animalScript := widget.NewMultiLineEntry()
+	animalScript.SetPlaceHolder("Script, e.g. when endurance < 20: rest (optional)")
 	
-	content := container.NewVBox(animalName, animalMinSpeed, animalMaxSpeed, widget.NewButtonWithIcon("Save", theme.ConfirmIcon(), func() {
+	content := container.NewVBox(animalName, animalMinSpeed, animalMaxSpeed, animalStamina, animalFatigueRate, animalRecoveryRate, animalAffinities, animalEventChances, animalStrategy, animalScript, widget.NewButtonWithIcon("Save", theme.ConfirmIcon(), func() {
+		affinities, err := simulation.ParseAffinities(animalAffinities.Text)
+		if err != nil {
+			dialog.ShowError(err, window)
+			return
+		}
+		eventChances, err := simulation.ParseEventChances(animalEventChances.Text)
+		if err != nil {
+			dialog.ShowError(err, window)
+			return
+		}
+		if err := simulation.ValidateScript(animalScript.Text); err != nil {
+			dialog.ShowError(err, window)
+			return
+		}
 		minSpeed, _ := strconv.ParseFloat(animalMinSpeed.Text, 64)
 		maxSpeed, _ := strconv.ParseFloat(animalMaxSpeed.Text, 64)
 		
@@ -68,11 +96,19 @@ func AddAnimal(hareandtortoise fyne.App, window fyne.Window) {
 			dialog.NewError(fmt.Errorf("maximum speed cannot be 0 or below"), window)
 		}
 
+		// blank or invalid endurance attributes get the defaults
+		stamina, fatigueRate, recoveryRate := simulation.ParseEnduranceAttributes(animalStamina.Text, animalFatigueRate.Text, animalRecoveryRate.Text, minSpeed)
+		if err := simulation.ValidateEndurance(minSpeed, stamina, fatigueRate, recoveryRate, affinities); err != nil {
+			dialog.ShowError(err, window)
+			return
+		}
+
 		// Convert back to string for saving
-		simulation.CreateAnimal(animalName.Text, strconv.FormatFloat(minSpeed, 'f', -1, 64), strconv.FormatFloat(maxSpeed, 'f', -1, 64))
+		simulation.CreateAnimal(animalName.Text, strconv.FormatFloat(minSpeed, 'f', -1, 64), strconv.FormatFloat(maxSpeed, 'f', -1, 64),
+			strconv.FormatFloat(stamina, 'f', -1, 64), strconv.FormatFloat(fatigueRate, 'f', -1, 64), strconv.FormatFloat(recoveryRate, 'f', -1, 64), simulation.FormatAffinities(affinities), simulation.FormatEventChances(eventChances), animalStrategy.Selected, animalScript.Text)
 		window.Hide()
 	}))	
 	window.SetContent(content)
-	window.Resize(fyne.NewSize(300, 250))
+	window.Resize(fyne.NewSize(300, 350))
 	window.CenterOnScreen()
 }
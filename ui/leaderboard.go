@@ -11,37 +11,44 @@ import (
 	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
+	"hareandtortoise/v2/simulation"
 )
 // define the Player data structure type
 type Player struct {
-	Name     string
-	Score    int
-	MinSpeed float64
-	MaxSpeed float64
-	UUID     string
+	Name         string
+	Score        float64
+	MinSpeed     float64
+	MaxSpeed     float64
+	UUID         string
+	Stamina      float64
+	FatigueRate  float64
+	RecoveryRate float64
+	Affinities   map[string]float64 // speed multiplier by weather
+	EventChances map[string]float64 // chance each round of each random event
+	Strategy     string             // default pacing strategy, blank runs flat out
+	Script       string             // racing rules, see simulation.Script
 }
 
-// ReadCSV reads the CSV file and returns a slice of Players
-func ReadCSV(filename string) ([]Player, error) {
-	file, err := os.Open(filename)
-	if err != nil {
-		return nil, err
-	}
-	defer file.Close()
+// animalRecord turns a player back into an animal.simulation row
+func animalRecord(player Player) []string {
+	return simulation.AnimalRecord(simulation.Player{Name: player.Name, Score: player.Score, MinSpeed: player.MinSpeed, MaxSpeed: player.MaxSpeed, UUID: player.UUID,
+		Stamina: player.Stamina, FatigueRate: player.FatigueRate, RecoveryRate: player.RecoveryRate, Affinities: player.Affinities, EventChances: player.EventChances,
+		Strategy: player.Strategy, Script: player.Script})
+}
 
-	reader := csv.NewReader(file)
-	records, err := reader.ReadAll()
+// ReadCSV reads the CSV file and returns a slice of Players, parsed by
+// simulation.ReadCSV so the roster is read the same way everywhere
+func ReadCSV(filename string) ([]Player, error) {
+	animals, err := simulation.ReadCSV(filename)
 	if err != nil {
 		return nil, err
 	}
 
 	var players []Player
-	for _, record := range records[1:] { 
-		//casting into integers and floats
-		score, _ := strconv.Atoi(record[1]) 
-		minSpeed, _ := strconv.ParseFloat(record[2], 64)
-		maxSpeed, _ := strconv.ParseFloat(record[3], 64)
-		players = append(players, Player{Name: record[0], Score: score, MinSpeed: minSpeed, MaxSpeed: maxSpeed, UUID: record[4]})
+	for _, animal := range animals {
+		players = append(players, Player{Name: animal.Name, Score: animal.Score, MinSpeed: animal.MinSpeed, MaxSpeed: animal.MaxSpeed, UUID: animal.UUID,
+			Stamina: animal.Stamina, FatigueRate: animal.FatigueRate, RecoveryRate: animal.RecoveryRate, Affinities: animal.Affinities, EventChances: animal.EventChances,
+			Strategy: animal.Strategy, Script: animal.Script})
 	}
 	return players, nil
 }
@@ -58,17 +65,9 @@ func UpdateCSV(filename string, players []Player) error {
 	defer writer.Flush()
 
 	// Write header
-	writer.Write([]string{"Name", "Score", "Min Speed", "Max Speed", "UUID"})
+	writer.Write(simulation.AnimalHeader)
 	for _, player := range players {
-		record := []string{
-			player.Name,
-			// casting into strings
-			strconv.Itoa(player.Score),
-			strconv.FormatFloat(player.MinSpeed, 'f', -1, 64),
-			strconv.FormatFloat(player.MaxSpeed, 'f', -1, 64),
-			player.UUID,
-		}
-		writer.Write(record)
+		writer.Write(animalRecord(player))
 	}
 	return nil
 }
@@ -85,9 +84,46 @@ func ShowEmbeddedEditForm(list *widget.Table, player *Player, players []Player,
 	maxSpeedEntry := widget.NewEntry()
 	maxSpeedEntry.SetText(strconv.FormatFloat(player.MaxSpeed, 'f', -1, 64))
 
+	staminaEntry := widget.NewEntry()
+	staminaEntry.SetText(strconv.FormatFloat(player.Stamina, 'f', -1, 64))
+
+	fatigueRateEntry := widget.NewEntry()
+	fatigueRateEntry.SetText(strconv.FormatFloat(player.FatigueRate, 'f', -1, 64))
+
+	recoveryRateEntry := widget.NewEntry()
+	recoveryRateEntry.SetText(strconv.FormatFloat(player.RecoveryRate, 'f', -1, 64))
+
+	affinitiesEntry := widget.NewEntry()
+	affinitiesEntry.SetPlaceHolder("e.g. Rain:1.2;Heat:0.8")
+	affinitiesEntry.SetText(simulation.FormatAffinities(player.Affinities))
+
+	eventChancesEntry := widget.NewEntry()
+	eventChancesEntry.SetPlaceHolder("e.g. Nap:0.5;Burst:0.1, blank for the defaults")
+	eventChancesEntry.SetText(simulation.FormatEventChances(player.EventChances))
+
+	strategySelect := widget.NewSelect(simulation.Strategies, nil)
+	strategySelect.SetSelected(simulation.StrategyName(player.Strategy))
+
+	scriptEntry := widget.NewMultiLineEntry()
+	scriptEntry.SetPlaceHolder("e.g. when endurance < 20: rest\neffort 0.8")
+	scriptEntry.SetText(player.Script)
+
 	// Save button
 	saveButton := widget.NewButton("Save", func() {
-		player.Name = nameEntry.Text
+		affinities, err := simulation.ParseAffinities(affinitiesEntry.Text)
+		if err != nil {
+			dialog.ShowError(err, formWindow)
+			return
+		}
+		eventChances, err := simulation.ParseEventChances(eventChancesEntry.Text)
+		if err != nil {
+			dialog.ShowError(err, formWindow)
+			return
+		}
+		if err := simulation.ValidateScript(scriptEntry.Text); err != nil {
+			dialog.ShowError(err, formWindow)
+			return
+		}
 		minSpeed, _ := strconv.ParseFloat(minSpeedEntry.Text, 64)
 		maxSpeed, _ := strconv.ParseFloat(maxSpeedEntry.Text, 64)
 
@@ -106,17 +142,32 @@ func ShowEmbeddedEditForm(list *widget.Table, player *Player, players []Player,
 			dialog.NewError(fmt.Errorf("maximum speed cannot be 0 or below"), formWindow)
 		}
 
+		// blank or invalid endurance attributes go back to the defaults
+		stamina, fatigueRate, recoveryRate := simulation.ParseEnduranceAttributes(
+			staminaEntry.Text, fatigueRateEntry.Text, recoveryRateEntry.Text, minSpeed)
+		if err := simulation.ValidateEndurance(minSpeed, stamina, fatigueRate, recoveryRate, affinities); err != nil {
+			dialog.ShowError(err, formWindow)
+			return
+		}
+
+		player.Affinities = affinities
+		player.EventChances = eventChances
+		player.Strategy = strategySelect.Selected
+		player.Script = scriptEntry.Text
+		player.Name = nameEntry.Text
 		player.MinSpeed = minSpeed
 		player.MaxSpeed = maxSpeed
+		player.Stamina, player.FatigueRate, player.RecoveryRate = stamina, fatigueRate, recoveryRate
 
 		// Save the changes back to the CSV file
 		if err := SavePlayersToCSV(filename, players); err != nil {
 		}
 
 		// Refresh the playerData after saving
-		*playerData = [][]string{{"Name", "Score", "Min Speed", "Max Speed", "UUID"}} // Header row
+		ratings := loadRatings()
+		*playerData = [][]string{leaderboardHeader} // Header row
 		for _, p := range players {
-			*playerData = append(*playerData, []string{p.Name, strconv.Itoa(p.Score), strconv.FormatFloat(p.MinSpeed, 'g', -1, 64), strconv.FormatFloat(p.MaxSpeed, 'g', -1, 64), p.UUID})
+			*playerData = append(*playerData, leaderboardRow(p, ratings))
 		}
 
 		list.Refresh() // Refresh the list with the updated playerData
@@ -138,9 +189,10 @@ func ShowEmbeddedEditForm(list *widget.Table, player *Player, players []Player,
 		}
 
 		// Refresh the playerData after deletion
-		*playerData = [][]string{{"Name", "Score", "Min Speed", "Max Speed", "UUID"}} // Header row
+		ratings := loadRatings()
+		*playerData = [][]string{leaderboardHeader} // Header row
 		for _, p := range players {
-			*playerData = append(*playerData, []string{p.Name, strconv.Itoa(p.Score), strconv.FormatFloat(p.MinSpeed, 'g', -1, 64), strconv.FormatFloat(p.MaxSpeed, 'g', -1, 64), p.UUID})
+			*playerData = append(*playerData, leaderboardRow(p, ratings))
 		}
 
 		list.Refresh() // Refresh the list with the updated playerData
@@ -154,6 +206,13 @@ func ShowEmbeddedEditForm(list *widget.Table, player *Player, players []Player,
 			widget.NewFormItem("Name", nameEntry),
 			widget.NewFormItem("Min Speed", minSpeedEntry),
 			widget.NewFormItem("Max Speed", maxSpeedEntry),
+			widget.NewFormItem("Stamina", staminaEntry),
+			widget.NewFormItem("Fatigue Rate", fatigueRateEntry),
+			widget.NewFormItem("Recovery Rate", recoveryRateEntry),
+			widget.NewFormItem("Weather Affinity", affinitiesEntry),
+			widget.NewFormItem("Event Chances", eventChancesEntry),
+			widget.NewFormItem("Strategy", strategySelect),
+			widget.NewFormItem("Script", scriptEntry),
 		),
 		saveButton,
 		deleteButton,
@@ -161,31 +220,61 @@ func ShowEmbeddedEditForm(list *widget.Table, player *Player, players []Player,
 
 	// Create a pop-up window or panel in your main UI to display the form
 	formWindow.SetContent(editForm)
-	formWindow.Resize(fyne.NewSize(300, 200))
+	formWindow.Resize(fyne.NewSize(300, 300))
 	formWindow.CenterOnScreen()
 	formWindow.Show()
 }
 
+// leaderboardHeader is the header row of the leaderboard table
+var leaderboardHeader = []string{"Name", "Score", "Rating", "Min Speed", "Max Speed", "UUID"}
+
+// leaderboardRow is a player's row in the leaderboard table, animals that haven't raced have no rating yet
+func leaderboardRow(player Player, ratings map[string]float64) []string {
+	rating := "-"
+	if r, ok := ratings[player.UUID]; ok {
+		rating = strconv.FormatFloat(r, 'f', 0, 64)
+	}
+	return []string{player.Name, strconv.FormatFloat(player.Score, 'f', -1, 64), rating, strconv.FormatFloat(player.MinSpeed, 'g', -1, 64), strconv.FormatFloat(player.MaxSpeed, 'g', -1, 64), player.UUID}
+}
+
+// playerRating is a player's current rating, or the starting rating if they haven't raced
+func playerRating(player Player, ratings map[string]float64) float64 {
+	if r, ok := ratings[player.UUID]; ok {
+		return r
+	}
+	return simulation.DefaultRating
+}
+
+// loadRatings reads everyone's current rating, an unreadable ratings file just leaves the column blank
+func loadRatings() map[string]float64 {
+	history, err := simulation.ReadRatingHistory(simulation.RatingsFile)
+	if err != nil {
+		fmt.Println("Error reading ratings:", err)
+	}
+	return simulation.CurrentRatings(history)
+}
+
 // UpdateLeaderboardContent dynamically updates the table with new data
 func UpdateLeaderboardContent(list *widget.Table, playerData [][]string) {
 	list.Refresh()
 }
 //leaderboard container
-func DisplayLeaderboard() *fyne.Container {
+func DisplayLeaderboard(myWindow fyne.Window) *fyne.Container {
 	//header row
-	playerData := [][]string{{"Name", "Score", "Min Speed", "Max Speed", "UUID"}}
+	playerData := [][]string{leaderboardHeader}
 	players, err := ReadCSV("data/animal.simulation")
 	if err != nil {
 		return nil
 	}
+	ratings := loadRatings()
 
 	for _, player := range players {
-		playerData = append(playerData, []string{player.Name, strconv.Itoa(player.Score), strconv.FormatFloat(player.MinSpeed, 'f', -1, 64), strconv.FormatFloat(player.MaxSpeed, 'f', -1, 64), player.UUID})
+		playerData = append(playerData, leaderboardRow(player, ratings))
 	}
 
 	// Create a widget to show leaderboard data
 	list := widget.NewTable(
-		func() (int, int) { return len(playerData), 5 },
+		func() (int, int) { return len(playerData), len(leaderboardHeader) },
 		func() fyne.CanvasObject { return widget.NewLabel("") },
 		func(id widget.TableCellID, o fyne.CanvasObject) {
 			o.(*widget.Label).SetText(playerData[id.Row][id.Col])
@@ -203,21 +292,34 @@ func DisplayLeaderboard() *fyne.Container {
 			return players[i].Score > players[j].Score
 		})
 		// Update the playerData slice after sorting
-		playerData = [][]string{{"Name", "Score", "Min Speed", "Max Speed", "UUID"}} // Header row
+		playerData = [][]string{leaderboardHeader} // Header row
 		for _, player := range players {
-			playerData = append(playerData, []string{player.Name, strconv.Itoa(player.Score), strconv.FormatFloat(player.MinSpeed, 'g', -1, 64), strconv.FormatFloat(player.MaxSpeed, 'g', -1, 64), player.UUID})
+			playerData = append(playerData, leaderboardRow(player, ratings))
 		}
 		UpdateLeaderboardContent(list, playerData) // Refresh the list with the updated playerData
 	})
 	
+	sortByRatingButton := widget.NewButton("Sort by Rating", func() {
+		sort.Slice(players, func(i, j int) bool {
+			// Sort by rating (descending), animals that haven't raced are on the starting rating
+			return playerRating(players[i], ratings) > playerRating(players[j], ratings)
+		})
+		// Update the playerData slice after sorting
+		playerData = [][]string{leaderboardHeader} // Header row
+		for _, player := range players {
+			playerData = append(playerData, leaderboardRow(player, ratings))
+		}
+		UpdateLeaderboardContent(list, playerData) // Refresh the list with the updated playerData
+	})
+
 	sortByNameButton := widget.NewButton("Sort by Name", func() {
 		sort.Slice(players, func(i, j int) bool {
 			return players[i].Name < players[j].Name // Sort by name (alphabetical)
 		})
 		// Update the playerData slice after sorting
-		playerData = [][]string{{"Name", "Score", "Min Speed", "Max Speed", "UUID"}} // Header row
+		playerData = [][]string{leaderboardHeader} // Header row
 		for _, player := range players {
-			playerData = append(playerData, []string{player.Name, strconv.Itoa(player.Score), strconv.FormatFloat(player.MinSpeed, 'g', -1, 64), strconv.FormatFloat(player.MaxSpeed, 'g', -1, 64), player.UUID})
+			playerData = append(playerData, leaderboardRow(player, ratings))
 		}
 		UpdateLeaderboardContent(list, playerData) // Refresh the list with the updated playerData
 	})
@@ -227,9 +329,9 @@ func DisplayLeaderboard() *fyne.Container {
 			return players[i].UUID < players[j].UUID // Sort by UUID (alphabetical)
 		})
 		// Update the playerData slice after sorting
-		playerData = [][]string{{"Name", "Score", "Min Speed", "Max Speed", "UUID"}} // Header row
+		playerData = [][]string{leaderboardHeader} // Header row
 		for _, player := range players {
-			playerData = append(playerData, []string{player.Name, strconv.Itoa(player.Score), strconv.FormatFloat(player.MinSpeed, 'g', -1, 64), strconv.FormatFloat(player.MaxSpeed, 'g', -1, 64), player.UUID})
+			playerData = append(playerData, leaderboardRow(player, ratings))
 		}
 		UpdateLeaderboardContent(list, playerData) // Refresh the list with the updated playerData
 	})
@@ -240,9 +342,10 @@ func DisplayLeaderboard() *fyne.Container {
 			if err != nil {
 				return
 			}
-			playerData = [][]string{{"Name", "Score", "Min Speed", "Max Speed", "UUID"}} // Header row
+			ratings = loadRatings()
+			playerData = [][]string{leaderboardHeader} // Header row
 			for _, player := range players {
-				playerData = append(playerData, []string{player.Name, strconv.Itoa(player.Score), strconv.FormatFloat(player.MinSpeed, 'g', -1, 64), strconv.FormatFloat(player.MaxSpeed, 'g', -1, 64), player.UUID})
+				playerData = append(playerData, leaderboardRow(player, ratings))
 			}
 			list.Refresh() // Refresh the list with the updated playerData
 		}),
@@ -275,6 +378,28 @@ func DisplayLeaderboard() *fyne.Container {
 			editWindow.Show()
 		}),
 		widget.NewToolbarSeparator(),
+		// rating history chart
+		widget.NewToolbarAction(theme.VisibilityIcon(), func() {
+			ShowRatingHistory(fyne.CurrentApp(), players)
+		}),
+		// rebuild the ratings from every saved race
+		widget.NewToolbarAction(theme.ViewRefreshIcon(), func() {
+			dialog.ShowConfirm("Recompute ratings", "Replay every saved race to work out the ratings again?", func(confirmed bool) {
+				if !confirmed {
+					return
+				}
+				if err := simulation.RecomputeRatings(); err != nil {
+					dialog.ShowError(err, myWindow)
+					return
+				}
+				ratings = loadRatings()
+				playerData = [][]string{leaderboardHeader} // Header row
+				for _, player := range players {
+					playerData = append(playerData, leaderboardRow(player, ratings))
+				}
+				list.Refresh()
+			}, myWindow)
+		}),
 	)
 
 	// Setting column widths
@@ -282,11 +407,12 @@ func DisplayLeaderboard() *fyne.Container {
 	list.SetColumnWidth(1, 140)
 	list.SetColumnWidth(2, 140)
 	list.SetColumnWidth(3, 140)
-	list.SetColumnWidth(4, 280)
+	list.SetColumnWidth(4, 140)
+	list.SetColumnWidth(5, 280)
 
 	// Display list and sorting buttons
 	content := container.NewBorder(
-		container.NewHBox(toolbar, sortByNameButton, sortByScoreButton, sortByUUIDButton),
+		container.NewHBox(toolbar, sortByNameButton, sortByScoreButton, sortByRatingButton, sortByUUIDButton),
 		nil, nil, nil,
 		list,
 	)
@@ -305,17 +431,11 @@ func SavePlayersToCSV(filename string, players []Player) error {
 	defer writer.Flush()
 
 	// Write header
-	writer.Write([]string{"Name", "Score", "Min Speed", "Max Speed", "UUID"})
+	writer.Write(simulation.AnimalHeader)
 
 	// Write player data
 	for _, player := range players {
-		writer.Write([]string{
-			player.Name,
-			strconv.Itoa(player.Score),
-			strconv.FormatFloat(player.MinSpeed, 'f', -1, 64),
-			strconv.FormatFloat(player.MaxSpeed, 'f', -1, 64),
-			player.UUID,
-		})
+		writer.Write(animalRecord(player))
 	}
 
 	return nil
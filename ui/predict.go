@@ -0,0 +1,77 @@
+package ui
+// import some stuff
+import (
+	"fmt"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"hareandtortoise/v2/simulation"
+	"sort"
+	"strings"
+)
+
+// ShowPrediction runs the Monte Carlo estimator in the background and shows the odds for each animal
+func ShowPrediction(app fyne.App, parent fyne.Window, players []simulation.Player, config simulation.RaceConfig) {
+	progress := dialog.NewCustomWithoutButtons("Predicting",
+		container.NewVBox(
+			widget.NewLabel(fmt.Sprintf("Running %d races...", simulation.DefaultPredictionRuns)),
+			widget.NewProgressBarInfinite(),
+		), parent)
+	progress.Show()
+
+	go func() {
+		result := simulation.EstimateWinProbabilities(players, config, simulation.DefaultPredictionRuns)
+		progress.Hide()
+
+		// favourites first
+		predictions := append([]simulation.Prediction(nil), result.Players...)
+		sort.Slice(predictions, func(i, j int) bool {
+			return predictions[i].WinProbability > predictions[j].WinProbability
+		})
+
+		// header row then one row per animal
+		rows := [][]string{{"Name", "Win", "Expected Place", "DNF", "Place Distribution"}}
+		for _, prediction := range predictions {
+			places := make([]string, len(prediction.PlaceDistribution))
+			for i, chance := range prediction.PlaceDistribution {
+				places[i] = fmt.Sprintf("%.0f%%", chance*100)
+			}
+			rows = append(rows, []string{
+				prediction.Name,
+				fmt.Sprintf("%.1f%%", prediction.WinProbability*100),
+				fmt.Sprintf("%.2f", prediction.ExpectedPlace),
+				fmt.Sprintf("%.1f%%", prediction.DNFRate*100),
+				strings.Join(places, " / "),
+			})
+		}
+
+		table := widget.NewTable(
+			func() (int, int) { return len(rows), 5 },
+			func() fyne.CanvasObject { return widget.NewLabel("") },
+			func(id widget.TableCellID, o fyne.CanvasObject) {
+				o.(*widget.Label).SetText(rows[id.Row][id.Col])
+				if id.Row == 0 {
+					// Bold headers
+					o.(*widget.Label).TextStyle = fyne.TextStyle{Bold: true}
+				}
+			},
+		)
+		table.SetColumnWidth(0, 140)
+		table.SetColumnWidth(1, 80)
+		table.SetColumnWidth(2, 120)
+		table.SetColumnWidth(3, 80)
+		table.SetColumnWidth(4, 300)
+
+		summaryText := fmt.Sprintf("%d races of %dm, expected length %.1f rounds", result.Runs, config.TotalDistance, result.ExpectedRounds)
+		if result.CalledOff > 0 {
+			summaryText += fmt.Sprintf(", %d called off with animals unable to finish", result.CalledOff)
+		}
+		summary := widget.NewLabel(summaryText)
+		predictionWindow := app.NewWindow("Race Prediction")
+		predictionWindow.SetContent(container.NewBorder(summary, nil, nil, nil, table))
+		predictionWindow.Resize(fyne.NewSize(760, 300))
+		predictionWindow.CenterOnScreen()
+		predictionWindow.Show()
+	}()
+}
@@ -7,9 +7,12 @@ import (
     "fyne.io/fyne/v2"
     "fyne.io/fyne/v2/container"
     "fyne.io/fyne/v2/dialog"
+    "fyne.io/fyne/v2/theme"
     "fyne.io/fyne/v2/widget"
+    "hareandtortoise/v2/simulation"
     "os"
     "path/filepath"
+    "sort"
     "strconv"
 	"fmt"
     "strings"
@@ -19,12 +22,23 @@ type Race struct {
     UUID               string
     Place              int
     DistanceTravelled  float64
-    Score              int
+    Score              float64
     TotalDistance      float64
     Rounds             int
     Date               string
     Time               string
     Name               string
+    Seed               int64
+    Model              string
+    FinishTime         float64
+    Placing            string // e.g. "=2nd", blank in races saved before placings were recorded
+    Status             string // Finished or DNF, blank in races saved before it was recorded
+    Mode               string // Standard or Timed, blank in races saved before timed races
+    RoundLimit         int
+    EliminatedRound    int    // round an elimination race knocked the animal out, 0 if it didn't
+    Handicap           float64 // head start in metres
+    Weather            string  // Sunny in races saved before weather was added
+    Strategy           string  // Flat Out in races saved before pacing strategies
 }
 // animal data strucutre
 type Animal struct {
@@ -33,11 +47,24 @@ type Animal struct {
 }
 // animal insights data structure
 type AnimalInsights struct {
-    TotalScore         int
+    TotalScore         float64
     RacesParticipated  int
     BestPlace          int
+    DNFs               int
     RaceData           []Race
     Last10Positions    []int
+    Last10Placings     []string
+    ByMode             map[string]*GroupInsights
+    ByWeather          map[string]*GroupInsights
+    ByStrategy         map[string]*GroupInsights
+}
+// insights for the races that share something, like the race mode
+type GroupInsights struct {
+    Races              int
+    Wins               int
+    TotalScore         float64
+    TotalDistance      float64
+    BestPlace          int
 }
 
 // ReadRaceFiles reads all .simulation files in the data/ directory.
@@ -95,18 +122,38 @@ func parseRaceFile(filename string) ([]Race, error) {
         return nil, errors.New("no race data found")
     }
 
+    // newer race files have extra columns on the end so look those up by name
+    columns := make(map[string]int)
+    for i, name := range records[0] {
+        columns[name] = i
+    }
+
     var races []Race
     for _, record := range records[1:] {
-        if len(record) != 9 { // Adjusted to expect 9 fields including Name
+        if len(record) < 9 { // Expect at least the original 9 fields including Name
             fmt.Printf("Skipping malformed record in %s: %+v\n", filename, record)
             continue
         }
 
         place, _ := strconv.Atoi(record[1])
         distanceTravelled, _ := strconv.ParseFloat(record[2], 64)
-        score, _ := strconv.Atoi(record[3])
+        score, _ := strconv.ParseFloat(record[3], 64)
         totalDistance, _ := strconv.ParseFloat(record[4], 64)
         rounds, _ := strconv.Atoi(record[5])
+        seed, _ := strconv.ParseInt(simulation.Column(record, columns, "Seed"), 10, 64)
+        finishTime, _ := strconv.ParseFloat(simulation.Column(record, columns, "Finish Time"), 64)
+        roundLimit, _ := strconv.Atoi(simulation.Column(record, columns, "Round Limit"))
+        eliminatedRound, _ := strconv.Atoi(simulation.Column(record, columns, "Eliminated Round"))
+        handicap, _ := strconv.ParseFloat(simulation.Column(record, columns, "Handicap"), 64)
+        mode := simulation.Column(record, columns, "Mode")
+        if mode == "" {
+            mode = simulation.ModeStandard
+        }
+        weather := simulation.Column(record, columns, "Weather")
+        if weather == "" {
+            weather = simulation.WeatherSunny
+        }
+        strategy := simulation.StrategyName(simulation.Column(record, columns, "Strategy"))
 
         races = append(races, Race{
             UUID:              record[0],
@@ -118,6 +165,17 @@ func parseRaceFile(filename string) ([]Race, error) {
             Date:              record[6],
             Time:              record[7],
             Name:              record[8], // Add Name field here if needed in Race struct
+            Seed:              seed,
+            Model:             simulation.Column(record, columns, "Model"),
+            FinishTime:        finishTime,
+            Placing:           simulation.Column(record, columns, "Placing"),
+            Status:            simulation.Column(record, columns, "Status"),
+            Mode:              mode,
+            RoundLimit:        roundLimit,
+            EliminatedRound:   eliminatedRound,
+            Handicap:          handicap,
+            Weather:           weather,
+            Strategy:          strategy,
         })
     }
 
@@ -158,7 +216,7 @@ func ReadAnimalData() (map[string]Animal, error) {
 
 // SearchAnimalInsights provides insights for a specific animal UUID or name.
 func SearchAnimalInsights(raceData map[string][]Race, animalID string, animalMap map[string]Animal) (AnimalInsights, error) {
-    var insights AnimalInsights
+    insights := AnimalInsights{ByMode: make(map[string]*GroupInsights), ByWeather: make(map[string]*GroupInsights), ByStrategy: make(map[string]*GroupInsights)}
     var foundAnimal bool
 
     for _, races := range raceData {
@@ -168,10 +226,23 @@ func SearchAnimalInsights(raceData map[string][]Race, animalID string, animalMap
                 foundAnimal = true
                 insights.TotalScore += race.Score
                 insights.RacesParticipated++
+                if race.Status == simulation.StatusDNF {
+                    insights.DNFs++
+                }
                 insights.Last10Positions = append(insights.Last10Positions, race.Place)
-                if insights.BestPlace == 0 || race.Place < insights.BestPlace {
+                placing := race.Placing
+                if placing == "" && race.Place > 0 {
+                    placing = simulation.FormatPlace(race.Place, false)
+                } else if placing == "" {
+                    placing = simulation.StatusDNF // older races left unfinished animals on place 0
+                }
+                insights.Last10Placings = append(insights.Last10Placings, placing)
+                if race.Place > 0 && (insights.BestPlace == 0 || race.Place < insights.BestPlace) {
                     insights.BestPlace = race.Place
                 }
+                addToGroup(insights.ByMode, race.Mode, race)
+                addToGroup(insights.ByWeather, race.Weather, race)
+                addToGroup(insights.ByStrategy, race.Strategy, race)
                 insights.RaceData = append(insights.RaceData, race)
             }
         }
@@ -190,6 +261,72 @@ func SearchAnimalInsights(raceData map[string][]Race, animalID string, animalMap
     return insights, nil
 }
 
+// addToGroup counts a race towards the group it belongs to
+func addToGroup(groups map[string]*GroupInsights, key string, race Race) {
+    group, ok := groups[key]
+    if !ok {
+        group = &GroupInsights{}
+        groups[key] = group
+    }
+    group.Races++
+    group.TotalScore += race.Score
+    group.TotalDistance += race.DistanceTravelled
+    if race.Place == 1 && race.Status != simulation.StatusDNF {
+        group.Wins++
+    }
+    if race.Place > 0 && race.Status != simulation.StatusDNF && (group.BestPlace == 0 || race.Place < group.BestPlace) {
+        group.BestPlace = race.Place
+    }
+}
+
+// formatGroups writes one line per group, in name order
+func formatGroups(title string, groups map[string]*GroupInsights) string {
+    names := make([]string, 0, len(groups))
+    for name := range groups {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+
+    text := title + ":"
+    for _, name := range names {
+        group := groups[name]
+        best := "-"
+        if group.BestPlace > 0 {
+            best = simulation.FormatPlace(group.BestPlace, false)
+        }
+        text += fmt.Sprintf("\n  %s: %d races, %d wins, best %s, avg score %.1f, avg distance %.1f",
+            name, group.Races, group.Wins, best, group.TotalScore/float64(group.Races), group.TotalDistance/float64(group.Races))
+    }
+    return text
+}
+
+// HistoricalPace is how many metres a round an animal has averaged over its
+// saved races, head starts taken off. Races without a finish time use the
+// rounds the whole race took. ok is false if the animal has no usable races.
+func HistoricalPace(raceData map[string][]Race, animalUUID string) (float64, bool) {
+    distance, rounds := 0.0, 0.0
+    for _, races := range raceData {
+        for _, race := range races {
+            if race.UUID != animalUUID {
+                continue
+            }
+            raceRounds := race.FinishTime
+            if raceRounds <= 0 {
+                raceRounds = float64(race.Rounds)
+            }
+            if raceRounds <= 0 {
+                continue
+            }
+            distance += race.DistanceTravelled - race.Handicap
+            rounds += raceRounds
+        }
+    }
+    if rounds == 0 || distance <= 0 {
+        return 0, false
+    }
+    return distance / rounds, true
+}
+
 // GetAnimalUUID returns the UUID for a given animal name from the animal map.
 func GetAnimalUUID(animalMap map[string]Animal, animalName string) (string, error) {
     for _, animal := range animalMap {
@@ -230,13 +367,70 @@ func SearchAnimals(myWindow fyne.Window) *fyne.Container {
 			return
 		}
 
-		results := fmt.Sprintf("Total Score: %d\nRaces Participated: %d\nBest Place: %d\nLast 10 Positions: %v", 
-			insights.TotalScore, insights.RacesParticipated, insights.BestPlace, insights.Last10Positions)
+		results := fmt.Sprintf("Total Score: %g\nRaces Participated: %d\nDid Not Finish: %d\nBest Place: %d\nLast 10 Positions: %v", 
+			insights.TotalScore, insights.RacesParticipated, insights.DNFs, insights.BestPlace, insights.Last10Placings)
+		results += "\n" + formatGroups("By Race Mode", insights.ByMode)
+		results += "\n" + formatGroups("By Weather", insights.ByWeather)
+		results += "\n" + formatGroups("By Strategy", insights.ByStrategy)
 		resultsLabel.SetText(results)
 	})
     
     searchContainer.Add(searchEntry)
 	searchContainer.Add(searchButton)
     searchContainer.Add(resultsLabel)
+    searchContainer.Add(widget.NewSeparator())
+    searchContainer.Add(ReplayRaces(myWindow))
 	return searchContainer
+}
+
+// raceLabels lists saved races newest first as "date time - uuid" so they can be picked from a dropdown
+func raceLabels(raceData map[string][]Race) ([]string, map[string]string) {
+    labels := []string{}
+    raceUUIDs := make(map[string]string)
+    for raceUUID, races := range raceData {
+        if len(races) == 0 {
+            continue
+        }
+        label := fmt.Sprintf("%s %s - %s", races[0].Date, races[0].Time, raceUUID)
+        labels = append(labels, label)
+        raceUUIDs[label] = raceUUID
+    }
+    sort.Sort(sort.Reverse(sort.StringSlice(labels)))
+    return labels, raceUUIDs
+}
+
+// ReplayRaces lets the user pick a saved race and watch it again
+func ReplayRaces(myWindow fyne.Window) *fyne.Container {
+    var raceUUIDs map[string]string
+    raceSelect := widget.NewSelect(nil, nil)
+    raceSelect.PlaceHolder = "Select a saved race..."
+
+    // reload the saved races so newly saved ones show up
+    refresh := func() {
+        raceData, err := ReadRaceFiles()
+        if err != nil {
+            dialog.ShowError(err, myWindow)
+            return
+        }
+        raceSelect.Options, raceUUIDs = raceLabels(raceData)
+        raceSelect.Refresh()
+    }
+    refresh()
+
+    refreshButton := widget.NewButtonWithIcon("", theme.ViewRefreshIcon(), refresh)
+    replayButton := widget.NewButtonWithIcon("Replay", theme.MediaPlayIcon(), func() {
+        raceUUID, ok := raceUUIDs[raceSelect.Selected]
+        if !ok {
+            dialog.ShowInformation("Error", "Please select a race to replay.", myWindow)
+            return
+        }
+        if err := simulation.ShowReplayWindow(fyne.CurrentApp(), raceUUID); err != nil {
+            dialog.ShowError(err, myWindow)
+        }
+    })
+
+    return container.NewVBox(
+        widget.NewLabel("Replay a race:"),
+        container.NewBorder(nil, nil, nil, container.NewHBox(refreshButton, replayButton), raceSelect),
+    )
 }
\ No newline at end of file
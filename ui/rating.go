@@ -0,0 +1,143 @@
+package ui
+// import some stuff
+import (
+	"fmt"
+	"image/color"
+	"math"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"hareandtortoise/v2/simulation"
+)
+
+// size of the rating chart and the gap left round it for the labels
+const (
+	chartWidth  = 640
+	chartHeight = 360
+	chartMargin = 40
+)
+
+// chartColors are the line colours, going round again if more animals are ticked
+var chartColors = []color.Color{
+	color.RGBA{230, 80, 70, 255},
+	color.RGBA{70, 140, 230, 255},
+	color.RGBA{80, 190, 100, 255},
+	color.RGBA{240, 170, 40, 255},
+	color.RGBA{170, 90, 210, 255},
+	color.RGBA{50, 190, 190, 255},
+}
+
+// ShowRatingHistory plots the ticked animals' ratings race by race, every
+// rated race is one step along so the lines can be compared
+func ShowRatingHistory(app fyne.App, players []Player) {
+	historyWindow := app.NewWindow("Rating History")
+
+	history, err := simulation.ReadRatingHistory(simulation.RatingsFile)
+	if err != nil {
+		dialog.ShowError(err, historyWindow)
+	}
+	// number the rated races in the order they were run
+	raceIndex := make(map[string]int)
+	for _, entry := range history {
+		if _, ok := raceIndex[entry.Race]; !ok {
+			raceIndex[entry.Race] = len(raceIndex) + 1
+		}
+	}
+
+	chart := container.NewWithoutLayout()
+	chart.Resize(fyne.NewSize(chartWidth+2*chartMargin, chartHeight+2*chartMargin))
+	selected := make(map[string]bool)
+
+	// draw redraws the chart for the ticked animals
+	draw := func() {
+		chart.RemoveAll()
+		low, high := simulation.DefaultRating, simulation.DefaultRating
+		for _, entry := range history {
+			if selected[entry.UUID] {
+				low, high = math.Min(low, entry.Rating), math.Max(high, entry.Rating)
+			}
+		}
+		if high-low < 50 {
+			low, high = low-25, high+25 // keep flat lines off the edges
+		}
+		races := math.Max(1, float64(len(raceIndex)))
+
+		point := func(race int, rating float64) fyne.Position {
+			x := chartMargin + float32(float64(race)/races)*chartWidth
+			y := chartMargin + float32((high-rating)/(high-low))*chartHeight
+			return fyne.NewPos(x, y)
+		}
+
+		// axes, with the starting rating marked
+		for _, axis := range [][2]fyne.Position{
+			{fyne.NewPos(chartMargin, chartMargin), fyne.NewPos(chartMargin, chartMargin+chartHeight)},
+			{fyne.NewPos(chartMargin, chartMargin+chartHeight), fyne.NewPos(chartMargin+chartWidth, chartMargin+chartHeight)},
+		} {
+			line := canvas.NewLine(color.Gray{Y: 160})
+			line.Position1, line.Position2 = axis[0], axis[1]
+			chart.Add(line)
+		}
+		start := canvas.NewLine(color.Gray{Y: 90})
+		start.Position1, start.Position2 = point(0, simulation.DefaultRating), point(int(races), simulation.DefaultRating)
+		chart.Add(start)
+		for _, rating := range []float64{high, simulation.DefaultRating, low} {
+			label := canvas.NewText(fmt.Sprintf("%.0f", rating), color.Gray{Y: 160})
+			label.TextSize = 10
+			label.Move(point(0, rating).SubtractXY(chartMargin-2, 7))
+			chart.Add(label)
+		}
+		racesLabel := canvas.NewText(fmt.Sprintf("%d races", len(raceIndex)), color.Gray{Y: 160})
+		racesLabel.TextSize = 10
+		racesLabel.Move(fyne.NewPos(chartMargin+chartWidth-40, chartMargin+chartHeight+4))
+		chart.Add(racesLabel)
+
+		// one line per animal, starting from the starting rating before its first race
+		for k, player := range players {
+			if !selected[player.UUID] {
+				continue
+			}
+			lineColor := chartColors[k%len(chartColors)]
+			from := fyne.Position{}
+			first := true
+			for _, entry := range history {
+				if entry.UUID != player.UUID {
+					continue
+				}
+				to := point(raceIndex[entry.Race], entry.Rating)
+				if first {
+					from = point(raceIndex[entry.Race]-1, entry.Rating-entry.Change)
+					first = false
+				}
+				segment := canvas.NewLine(lineColor)
+				segment.StrokeWidth = 2
+				segment.Position1, segment.Position2 = from, to
+				chart.Add(segment)
+				from = to
+			}
+		}
+		chart.Refresh()
+	}
+
+	animalCheckboxes := make([]fyne.CanvasObject, len(players))
+	for k, player := range players {
+		player := player
+		swatch := canvas.NewRectangle(chartColors[k%len(chartColors)])
+		swatch.SetMinSize(fyne.NewSize(12, 12))
+		check := widget.NewCheck(player.Name, func(checked bool) {
+			selected[player.UUID] = checked
+			draw()
+		})
+		animalCheckboxes[k] = container.NewHBox(container.NewCenter(swatch), check)
+	}
+	draw()
+
+	chartArea := container.NewGridWrap(fyne.NewSize(chartWidth+2*chartMargin, chartHeight+2*chartMargin), chart)
+	content := container.NewBorder(nil, nil, container.NewVScroll(container.NewVBox(animalCheckboxes...)), nil, chartArea)
+	historyWindow.SetContent(content)
+	historyWindow.Resize(fyne.NewSize(960, 480))
+	historyWindow.CenterOnScreen()
+	historyWindow.Show()
+}
@@ -0,0 +1,286 @@
+package ui
+// import some stuff
+import (
+	"fmt"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"hareandtortoise/v2/settings"
+	"hareandtortoise/v2/simulation"
+	"strconv"
+)
+
+// Seasons is the seasons tab, pick a season to see its table and fixtures and run them
+func Seasons(myWindow fyne.Window) fyne.CanvasObject {
+	var seasons []*simulation.Season
+	var current *simulation.Season
+
+	seasonSelect := widget.NewSelect(nil, nil)
+	seasonSelect.PlaceHolder = "Select a season..."
+	statusLabel := widget.NewLabel("")
+
+	// the standings table, the header row is row 0
+	var table [][]string
+	standings := widget.NewTable(
+		func() (int, int) { return len(table), 6 },
+		func() fyne.CanvasObject { return widget.NewLabel("") },
+		func(id widget.TableCellID, o fyne.CanvasObject) {
+			o.(*widget.Label).SetText(table[id.Row][id.Col])
+			o.(*widget.Label).TextStyle = fyne.TextStyle{Bold: id.Row == 0}
+		},
+	)
+	for col, width := range []float32{40, 150, 60, 60, 70, 60} {
+		standings.SetColumnWidth(col, width)
+	}
+	fixtures := container.NewVBox()
+
+	// show redraws the table and fixtures for the selected season
+	show := func() {
+		table = [][]string{{"Pos", "Name", "Races", "Wins", "Points", "Best"}}
+		fixtures.RemoveAll()
+		if current == nil {
+			statusLabel.SetText("")
+			standings.Refresh()
+			return
+		}
+
+		for k, row := range current.Standings() {
+			best := "-"
+			if row.BestPlace > 0 {
+				best = simulation.FormatPlace(row.BestPlace, false)
+			}
+			table = append(table, []string{strconv.Itoa(k + 1), row.Name, strconv.Itoa(row.Races), strconv.Itoa(row.Wins), strconv.FormatFloat(row.Points, 'f', -1, 64), best})
+		}
+		standings.Refresh()
+
+		for i, fixture := range current.Fixtures {
+			fixtures.Add(fixtureRow(current, i, fixture, myWindow))
+		}
+
+		played := 0
+		for _, fixture := range current.Fixtures {
+			if fixture.Done() {
+				played++
+			}
+		}
+		status := fmt.Sprintf("%d of %d fixtures run - %s scoring", played, len(current.Fixtures), current.Scoring.Name)
+		if current.IsClosed() {
+			status = fmt.Sprintf("Closed %s - %s", current.Closed, status)
+		}
+		statusLabel.SetText(status)
+	}
+
+	// reload the saved seasons, keeping the selection if it is still there
+	refresh := func() {
+		var err error
+		seasons, err = simulation.LoadSeasons()
+		if err != nil {
+			dialog.ShowError(err, myWindow)
+			return
+		}
+		labels := make([]string, len(seasons))
+		for i, s := range seasons {
+			labels[i] = seasonLabel(s)
+		}
+		seasonSelect.Options = labels
+		if current != nil {
+			for _, s := range seasons {
+				if s.UUID == current.UUID {
+					current = s
+				}
+			}
+		}
+		seasonSelect.Refresh()
+		show()
+	}
+	seasonSelect.OnChanged = func(selected string) {
+		for _, s := range seasons {
+			if seasonLabel(s) == selected {
+				current = s
+			}
+		}
+		show()
+	}
+	refresh()
+
+	refreshButton := widget.NewButtonWithIcon("", theme.ViewRefreshIcon(), refresh)
+	newButton := widget.NewButtonWithIcon("New Season", theme.ContentAddIcon(), func() {
+		ShowNewSeason(fyne.CurrentApp(), func(s *simulation.Season) {
+			refresh()
+			seasonSelect.SetSelected(seasonLabel(s))
+		})
+	})
+
+	runButton := widget.NewButtonWithIcon("Run Next Fixture", theme.MediaPlayIcon(), func() {
+		if current == nil {
+			dialog.ShowInformation("Error", "Please select a season.", myWindow)
+			return
+		}
+		tiePolicy, tick := leagueSettings()
+		err := simulation.RunFixture(fyne.CurrentApp(), current, tiePolicy, tick, func(err error) {
+			if err != nil {
+				dialog.ShowError(err, myWindow)
+			}
+			show()
+		})
+		if err != nil {
+			dialog.ShowError(err, myWindow)
+		}
+	})
+	simulateButton := widget.NewButtonWithIcon("Simulate Remaining", theme.MediaFastForwardIcon(), func() {
+		if current == nil {
+			dialog.ShowInformation("Error", "Please select a season.", myWindow)
+			return
+		}
+		if err := current.SimulateRemainingFixtures(leagueSettings()); err != nil {
+			dialog.ShowError(err, myWindow)
+		}
+		show()
+	})
+	closeButton := widget.NewButtonWithIcon("Close Season", theme.DocumentSaveIcon(), func() {
+		if current == nil {
+			dialog.ShowInformation("Error", "Please select a season.", myWindow)
+			return
+		}
+		dialog.NewConfirm("Close season", fmt.Sprintf("Close %s and archive its table? Fixtures not yet run won't be played.", current.Name),
+			func(confirmed bool) {
+				if !confirmed {
+					return
+				}
+				if err := current.Close(); err != nil {
+					dialog.ShowError(err, myWindow)
+				}
+				refresh()
+			}, myWindow).Show()
+	})
+
+	top := container.NewVBox(
+		container.NewBorder(nil, nil, nil, container.NewHBox(refreshButton, newButton), seasonSelect),
+		container.NewHBox(runButton, simulateButton, closeButton, statusLabel),
+	)
+	split := container.NewHSplit(standings, container.NewVScroll(fixtures))
+	split.Offset = 0.5
+	return container.NewBorder(top, nil, nil, nil, split)
+}
+
+// seasonLabel is how a season is listed in the dropdown
+func seasonLabel(s *simulation.Season) string {
+	if s.IsClosed() {
+		return fmt.Sprintf("%s - %s (closed)", s.Created, s.Name)
+	}
+	return fmt.Sprintf("%s - %s", s.Created, s.Name)
+}
+
+// fixtureRow lists a fixture's animals, in finishing order with points once
+// it has been run, with a button to replay its race
+func fixtureRow(s *simulation.Season, i int, fixture simulation.Fixture, window fyne.Window) fyne.CanvasObject {
+	text := fmt.Sprintf("Matchday %d, fixture %d: ", fixture.Matchday, i+1)
+	if !fixture.Done() {
+		for k, animal := range fixture.Entrants {
+			if k > 0 {
+				text += ", "
+			}
+			text += s.EntrantName(animal)
+		}
+		return widget.NewLabel(text)
+	}
+
+	for k, result := range fixture.Results {
+		if k > 0 {
+			text += ", "
+		}
+		placing := simulation.FormatPlace(result.Place, false)
+		if result.Status == simulation.StatusDNF {
+			placing = simulation.StatusDNF
+		}
+		text += fmt.Sprintf("%s %s (%g)", placing, s.EntrantName(result.UUID), result.Points)
+	}
+	raceUUID := fixture.RaceUUID
+	replayButton := widget.NewButtonWithIcon("", theme.MediaPlayIcon(), func() {
+		if err := simulation.ShowReplayWindow(fyne.CurrentApp(), raceUUID); err != nil {
+			dialog.ShowError(err, window)
+		}
+	})
+	return container.NewBorder(nil, nil, nil, replayButton, widget.NewLabel(text))
+}
+
+// ShowNewSeason lets the user pick the roster and settings for a new season,
+// onCreated gets it once it has been saved
+func ShowNewSeason(app fyne.App, onCreated func(*simulation.Season)) {
+	seasonWindow := app.NewWindow("New Season")
+
+	players, err := ReadCSV("data/animal.simulation")
+	if err != nil {
+		dialog.ShowError(err, seasonWindow)
+		return
+	}
+
+	nameEntry := widget.NewEntry()
+	nameEntry.SetPlaceHolder("Season name")
+	raceLengthEntry := newNumericalEntry()
+	raceLengthEntry.SetPlaceHolder("Race length for every fixture")
+	modelSelect := widget.NewSelect(simulation.MovementModelNames(), nil)
+	modelSelect.SetSelected(simulation.DefaultMovementModel)
+
+	// season points can use any scoring scheme, including the user's own tables
+	existingSettings, _ := settings.LoadSettings()
+	scoringSchemes := simulation.ScoringSchemes(existingSettings.ScoringTables)
+	scoringNames := make([]string, len(scoringSchemes))
+	for i, scheme := range scoringSchemes {
+		scoringNames[i] = scheme.Name
+	}
+	scoringSelect := widget.NewSelect(scoringNames, nil)
+	scoringSelect.SetSelected(simulation.LinearScoring.Name)
+
+	checks := make([]*widget.Check, len(players))
+	animalCheckboxes := make([]fyne.CanvasObject, len(players))
+	for i, player := range players {
+		checks[i] = widget.NewCheck(player.Name, nil)
+		animalCheckboxes[i] = checks[i]
+	}
+
+	createButton := widget.NewButtonWithIcon("Create", theme.ConfirmIcon(), func() {
+		var roster []simulation.Player
+		for i, check := range checks {
+			if check.Checked {
+				roster = append(roster, simulation.Player{Name: players[i].Name, UUID: players[i].UUID})
+			}
+		}
+		var scoring simulation.ScoringScheme
+		for _, scheme := range scoringSchemes {
+			if scheme.Name == scoringSelect.Selected {
+				scoring = scheme
+			}
+		}
+		raceLength, _ := strconv.Atoi(raceLengthEntry.Text)
+		s, err := simulation.NewSeason(nameEntry.Text, roster, raceLength, modelSelect.Selected, scoring)
+		if err != nil {
+			dialog.ShowError(err, seasonWindow)
+			return
+		}
+		if err := s.Save(); err != nil {
+			dialog.ShowError(err, seasonWindow)
+			return
+		}
+		onCreated(s)
+		seasonWindow.Close()
+	})
+
+	form := container.NewVBox(
+		nameEntry,
+		widget.NewLabel("Race Length (meters):"),
+		raceLengthEntry,
+		widget.NewLabel("Movement Model:"),
+		modelSelect,
+		widget.NewLabel("Scoring:"),
+		scoringSelect,
+		widget.NewLabel("Roster:"),
+	)
+	content := container.NewBorder(form, createButton, nil, nil, container.NewVScroll(container.NewVBox(animalCheckboxes...)))
+	seasonWindow.SetContent(content)
+	seasonWindow.Resize(fyne.NewSize(400, 600))
+	seasonWindow.CenterOnScreen()
+	seasonWindow.Show()
+}
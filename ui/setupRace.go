@@ -5,7 +5,9 @@ import (
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/widget"
-	"strconv"
+	"fmt"
+	"time"
+	"hareandtortoise/v2/settings"
 	"hareandtortoise/v2/simulation"
 )
 
@@ -22,6 +24,7 @@ func ShowSetupRaceMenu(app fyne.App) [][]string {
 
 	// Create animal selection checkboxes and convert them to fyne.CanvasObject
 	var selectedAnimals []Player
+	strategySelects := make(map[string]*widget.Select) // per entrant pacing, keyed by animal name
 	animalCheckboxes := make([]fyne.CanvasObject, len(players)) // This should be []fyne.CanvasObject
 	for i, player := range players {
 		checkbox := widget.NewCheck(player.Name, func(checked bool) {
@@ -37,49 +40,262 @@ func ShowSetupRaceMenu(app fyne.App) [][]string {
 				}
 			}
 		})
-		animalCheckboxes[i] = checkbox // Assign as a fyne.CanvasObject
+		strategySelect := widget.NewSelect(append([]string{animalDefaultStrategy}, simulation.Strategies...), nil)
+		strategySelect.SetSelected(animalDefaultStrategy)
+		strategySelects[player.Name] = strategySelect
+		animalCheckboxes[i] = container.NewGridWithColumns(2, checkbox, strategySelect) // Assign as a fyne.CanvasObject
 	}
 
+	// entrants are the selected animals with any strategy picked for this race in place of their own
+	entrants := func() []Player {
+		racing := make([]Player, len(selectedAnimals))
+		for i, player := range selectedAnimals {
+			if strategy := strategySelects[player.Name].Selected; strategy != animalDefaultStrategy {
+				player.Strategy = strategy
+			}
+			racing[i] = player
+		}
+		return racing
+	}
+	animalPicker := container.NewVBox(widget.NewLabel("Select Animals:"), container.NewVBox(animalCheckboxes...))
+
+	// relays are run by teams instead of single animals
+	teamsPicker, selectedTeams := teamPicker(app, setupWindow)
+	teamsPicker.Hide()
+
 	// Race length entry
 	raceLengthLabel := widget.NewLabel("Race Length (meters):")
 	raceLengthEntry := newNumericalEntry()
 	raceLengthEntry.SetPlaceHolder("Enter race length")
 
+	// Race mode, a timed race runs for a set number of rounds instead of to a finish line
+	modeLabel := widget.NewLabel("Race Mode:")
+	roundLimitEntry := newNumericalEntry()
+	roundLimitEntry.SetPlaceHolder("Rounds the timed race lasts")
+	roundLimitEntry.Disable()
+	eliminationEntry := newNumericalEntry()
+	eliminationEntry.SetPlaceHolder("Rounds between knockouts")
+	eliminationEntry.Disable()
+	modeSelect := widget.NewSelect(simulation.RaceModes, func(selected string) {
+		roundLimitEntry.Disable()
+		eliminationEntry.Disable()
+		animalPicker.Show()
+		teamsPicker.Hide()
+		raceLengthEntry.SetPlaceHolder("Optional, sets how long the track is drawn")
+		switch selected {
+		case simulation.ModeTimed:
+			roundLimitEntry.Enable()
+		case simulation.ModeElimination:
+			eliminationEntry.Enable()
+		case simulation.ModeRelay:
+			animalPicker.Hide()
+			teamsPicker.Show()
+			raceLengthEntry.SetPlaceHolder("Enter race length, split evenly between the legs")
+		default:
+			raceLengthEntry.SetPlaceHolder("Enter race length")
+		}
+	})
+	modeSelect.SetSelected(simulation.ModeStandard)
+
+	// Seed entry, the same seed and animals always give the same race
+	seedLabel := widget.NewLabel("Seed:")
+	seedEntry := newNumericalEntry()
+	seedEntry.SetPlaceHolder("Leave blank for a random race")
+
+	// Movement model selection
+	modelLabel := widget.NewLabel("Movement Model:")
+	modelSelect := widget.NewSelect(simulation.MovementModelNames(), nil)
+	modelSelect.SetSelected(simulation.DefaultMovementModel)
+
+	// Scoring scheme selection, the user's own points tables come from the settings
+	existingSettings, _ := settings.LoadSettings()
+	scoringSchemes := simulation.ScoringSchemes(existingSettings.ScoringTables)
+	scoringNames := make([]string, len(scoringSchemes))
+	for i, scheme := range scoringSchemes {
+		scoringNames[i] = scheme.Name
+	}
+	scoringLabel := widget.NewLabel("Scoring:")
+	scoringSelect := widget.NewSelect(scoringNames, nil)
+	scoringSelect.SetSelected(simulation.LinearScoring.Name)
+	// Handicap selection, head starts even out the field
+	handicapLabel := widget.NewLabel("Handicap:")
+	handicapSelect := widget.NewSelect(simulation.HandicapMethods, nil)
+	handicapSelect.SetSelected(simulation.HandicapNone)
+
+	// Track selection, the terrain along the race
+	trackLabel := widget.NewLabel("Track:")
+	tracksPicker, selectedTrack := trackPicker(app, setupWindow)
+
+	// Weather selection, random picks it from the seed
+	weatherLabel := widget.NewLabel("Weather:")
+	weatherSelect := widget.NewSelect(append(append([]string{}, simulation.Weathers...), simulation.WeatherRandom), nil)
+	weatherSelect.SetSelected(simulation.WeatherSunny)
+
+	eventsCheck := widget.NewCheck("Random events (naps, stumbles, bursts, distractions)", nil)
+
+	leadBonusCheck := widget.NewCheck(fmt.Sprintf("%g bonus points for leading the most rounds", simulation.LeadBonusPoints), nil)
+
+	// raceConfig reads the race settings from the form
+	raceConfig := func() (simulation.RaceConfig, error) {
+		config, err := simulation.ParseRaceConfig(raceLengthEntry.Text, seedEntry.Text)
+		if err != nil {
+			return config, err
+		}
+		config.Model = modelSelect.Selected
+		config.Mode = modeSelect.Selected
+		switch config.Mode {
+		case simulation.ModeTimed:
+			config.RoundLimit, err = simulation.ParseRounds(roundLimitEntry.Text)
+		case simulation.ModeElimination:
+			config.EliminationInterval, err = simulation.ParseRounds(eliminationEntry.Text)
+		}
+		if err != nil {
+			return config, err
+		}
+		for _, scheme := range scoringSchemes {
+			if scheme.Name == scoringSelect.Selected {
+				config.Scoring = scheme
+			}
+		}
+		config.LeadBonus = leadBonusCheck.Checked
+		// league wide tie policy and the race speed come from the settings
+		config.TiePolicy = existingSettings.TiePolicy
+		config.Tick = time.Duration(existingSettings.TickMillis) * time.Millisecond
+		config.Handicap = handicapSelect.Selected
+		config.Track = selectedTrack()
+		config.RandomEvents = eventsCheck.Checked
+		config.Weather = weatherSelect.Selected
+		if config.Weather == simulation.WeatherRandom {
+			config.Weather = simulation.RandomWeather(config.Seed)
+		}
+		return config, config.Validate()
+	}
+
+	// withHandicaps works out the head starts in the background, as it runs
+	// hundreds of solo races for every animal, then carries on with the config
+	withHandicaps := func(config simulation.RaceConfig, next func(simulation.RaceConfig)) {
+		if config.Handicap == "" || config.Handicap == simulation.HandicapNone {
+			next(config)
+			return
+		}
+		racing := entrants()
+		progress := dialog.NewCustomWithoutButtons("Handicapping",
+			container.NewVBox(
+				widget.NewLabel("Working out head starts..."),
+				widget.NewProgressBarInfinite(),
+			), setupWindow)
+		progress.Show()
+
+		go func() {
+			handicaps, err := raceHandicaps(racing, config)
+			progress.Hide()
+			if err != nil {
+				dialog.ShowError(err, setupWindow)
+				return
+			}
+			config.Handicaps = handicaps
+			next(config)
+		}()
+	}
+
 	// Start Race button
 	startRaceButton := widget.NewButton("Start Race", func() {
+		if modeSelect.Selected == simulation.ModeRelay {
+			teams := selectedTeams()
+			if len(teams) == 0 {
+				dialog.ShowInformation("Error", "Please select at least one team for the relay.", setupWindow)
+				return
+			}
+			config, err := raceConfig()
+			if err != nil {
+				dialog.ShowError(err, setupWindow)
+				return
+			}
+			if err := simulation.RunRelaySimulation(app, teams, 70, 1000, config); err != nil {
+				dialog.ShowError(err, setupWindow)
+				return
+			}
+			setupWindow.Close()
+			return
+		}
 		if len(selectedAnimals) == 0 {
 			dialog.ShowInformation("Error", "Please select at least one animal for the race.", setupWindow)
 			return
 		}
-		if raceLengthEntry.Text == "" {
-			dialog.ShowInformation("Error", "Please enter a valid race length.", setupWindow)
+		config, err := raceConfig()
+		if err != nil {
+			dialog.ShowError(err, setupWindow)
+			return
+		}
+		numberOfPlayers := len(selectedAnimals)
+		playerData := buildPlayerData(entrants())
+
+		withHandicaps(config, func(config simulation.RaceConfig) {
+			if err := simulation.RunSimulation(app, numberOfPlayers, 70, 1000, playerData, config); err != nil {
+				dialog.ShowError(err, setupWindow)
+				return
+			}
+			// Close the window
+			setupWindow.Close()
+		})
+	})
+
+	// Predict button runs the race thousands of times to estimate who will win
+	predictButton := widget.NewButton("Predict", func() {
+		relay := modeSelect.Selected == simulation.ModeRelay
+		if !relay && len(selectedAnimals) == 0 {
+			dialog.ShowInformation("Error", "Please select at least one animal for the race.", setupWindow)
+			return
+		}
+		if relay && len(selectedTeams()) == 0 {
+			dialog.ShowInformation("Error", "Please select at least one team for the relay.", setupWindow)
 			return
 		}
-		var numberOfPlayers int = 0
-		// Create playerData in the specified format
-		playerData := [][]string{{"Name", "Score", "Min Speed", "Max Speed", "UUID"}} // Header row
-		for _, player := range selectedAnimals {
-			playerData = append(playerData, []string{
-				player.Name,
-				strconv.Itoa(player.Score),
-				strconv.FormatFloat(player.MinSpeed, 'g', -1, 64),
-				strconv.FormatFloat(player.MaxSpeed, 'g', -1, 64),
-				player.UUID,
-			})
-			numberOfPlayers = numberOfPlayers + 1
-		}
-
-		simulation.RunSimulation(app, numberOfPlayers, 70, 1000, playerData, raceLengthEntry.Text)
-		// Close the window
-		setupWindow.Close()
+		config, err := raceConfig()
+		if err != nil {
+			dialog.ShowError(err, setupWindow)
+			return
+		}
+		var players []simulation.Player
+		if relay {
+			players, err = relayPlayers(selectedTeams())
+		} else {
+			players, err = simulation.CreatePlayers(buildPlayerData(entrants())[1:])
+		}
+		if err != nil {
+			dialog.ShowError(err, setupWindow)
+			return
+		}
+		withHandicaps(config, func(config simulation.RaceConfig) {
+			ShowPrediction(app, setupWindow, players, config)
+		})
 	})
 
 	// Organize UI components
 	content := container.NewVBox(
-		widget.NewLabel("Select Animals:"),
-		container.NewVBox(animalCheckboxes...), // Pass converted checkboxes
+		animalPicker,
+		teamsPicker,
+		modeLabel,
+		modeSelect,
 		raceLengthLabel,
 		raceLengthEntry,
+		roundLimitEntry,
+		eliminationEntry,
+		seedLabel,
+		seedEntry,
+		modelLabel,
+		modelSelect,
+		scoringLabel,
+		scoringSelect,
+		handicapLabel,
+		handicapSelect,
+		trackLabel,
+		tracksPicker,
+		weatherLabel,
+		weatherSelect,
+		eventsCheck,
+		leadBonusCheck,
+		predictButton,
 		startRaceButton,
 	)
 
@@ -90,3 +306,53 @@ func ShowSetupRaceMenu(app fyne.App) [][]string {
 	// Return empty playerData initially, will be updated when the race starts
 	return nil
 }
+
+// animalDefaultStrategy is the setup option that leaves an entrant on its own pacing strategy
+const animalDefaultStrategy = "Animal default"
+
+// raceHandicaps works out each selected animal's head start, history based
+// handicaps fall back to the animal's speeds if it has no saved races
+func raceHandicaps(selectedAnimals []Player, config simulation.RaceConfig) (map[string]float64, error) {
+	players, err := simulation.CreatePlayers(buildPlayerData(selectedAnimals)[1:])
+	if err != nil {
+		return nil, err
+	}
+	if config.Handicap != simulation.HandicapHistory {
+		return simulation.Handicaps(players, config, nil)
+	}
+
+	// an animal that has run slower than its speeds suggest gets its expected times stretched
+	raceData, err := ReadRaceFiles()
+	if err != nil {
+		return nil, err
+	}
+	paces, err := simulation.EstimatePaces(players, config)
+	if err != nil {
+		return nil, err
+	}
+	scale := make([]float64, len(players))
+	for i, player := range players {
+		if pace, ok := HistoricalPace(raceData, player.UUID); ok && paces[i] > 0 {
+			scale[i] = paces[i] / pace
+		}
+	}
+	return simulation.Handicaps(players, config, scale)
+}
+
+// relayPlayers builds a lane per team with its animals from the roster
+func relayPlayers(teams []simulation.Team) ([]simulation.Player, error) {
+	roster, err := simulation.ReadCSV("data/animal.simulation")
+	if err != nil {
+		return nil, err
+	}
+	return simulation.TeamPlayers(teams, roster)
+}
+
+// buildPlayerData turns the selected animals into rows for simulation.CreatePlayers, including the header row
+func buildPlayerData(selectedAnimals []Player) [][]string {
+	playerData := [][]string{simulation.AnimalHeader} // Header row
+	for _, player := range selectedAnimals {
+		playerData = append(playerData, animalRecord(player))
+	}
+	return playerData
+}
@@ -0,0 +1,132 @@
+package ui
+// import some stuff
+import (
+	"fmt"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"hareandtortoise/v2/simulation"
+	"strings"
+)
+
+// ShowCreateTeam lets the user name a relay team and pick its animals, the
+// order they are ticked is the order they run their legs
+func ShowCreateTeam(app fyne.App, onCreated func()) {
+	teamWindow := app.NewWindow("New Team")
+
+	players, err := ReadCSV("data/animal.simulation")
+	if err != nil {
+		dialog.ShowError(err, teamWindow)
+		return
+	}
+
+	teamName := widget.NewEntry()
+	teamName.SetPlaceHolder("Team name")
+	orderLabel := widget.NewLabel("Running order: none picked")
+
+	var members []Player
+	showOrder := func() {
+		names := make([]string, len(members))
+		for i, member := range members {
+			names[i] = member.Name
+		}
+		if len(names) == 0 {
+			orderLabel.SetText("Running order: none picked")
+			return
+		}
+		orderLabel.SetText("Running order: " + strings.Join(names, ", "))
+	}
+
+	animalCheckboxes := make([]fyne.CanvasObject, len(players))
+	for i, player := range players {
+		animalCheckboxes[i] = widget.NewCheck(player.Name, func(checked bool) {
+			if checked {
+				members = append(members, player)
+			} else {
+				for j, member := range members {
+					if member.UUID == player.UUID {
+						members = append(members[:j], members[j+1:]...)
+						break
+					}
+				}
+			}
+			showOrder()
+		})
+	}
+
+	saveButton := widget.NewButtonWithIcon("Save", theme.ConfirmIcon(), func() {
+		uuids := make([]string, len(members))
+		for i, member := range members {
+			uuids[i] = member.UUID
+		}
+		if err := simulation.CreateTeam(teamName.Text, uuids); err != nil {
+			dialog.ShowError(err, teamWindow)
+			return
+		}
+		if onCreated != nil {
+			onCreated()
+		}
+		teamWindow.Close()
+	})
+
+	content := container.NewVBox(
+		teamName,
+		widget.NewLabel("Select Animals:"),
+		container.NewVBox(animalCheckboxes...),
+		orderLabel,
+		saveButton,
+	)
+	teamWindow.SetContent(container.NewVScroll(content))
+	teamWindow.Resize(fyne.NewSize(400, 400))
+	teamWindow.CenterOnScreen()
+	teamWindow.Show()
+}
+
+// teamPicker shows a checkbox per team with a button to make a new one, the
+// returned func gives the ticked teams
+func teamPicker(app fyne.App, window fyne.Window) (*fyne.Container, func() []simulation.Team) {
+	var teams []simulation.Team
+	selected := make(map[string]bool)
+	checkboxes := container.NewVBox()
+
+	// reload the teams so a newly made one shows up
+	refresh := func() {
+		var err error
+		teams, err = simulation.ReadTeams(simulation.TeamsFile)
+		if err != nil {
+			dialog.ShowError(err, window)
+			return
+		}
+		checkboxes.RemoveAll()
+		if len(teams) == 0 {
+			checkboxes.Add(widget.NewLabel("No teams yet, make one to run a relay."))
+		}
+		for _, team := range teams {
+			team := team
+			check := widget.NewCheck(fmt.Sprintf("%s (%d legs)", team.Name, len(team.Members)), func(checked bool) {
+				selected[team.UUID] = checked
+			})
+			check.SetChecked(selected[team.UUID])
+			checkboxes.Add(check)
+		}
+	}
+	refresh()
+
+	newTeamButton := widget.NewButtonWithIcon("New Team", theme.ContentAddIcon(), func() {
+		ShowCreateTeam(app, refresh)
+	})
+
+	selectedTeams := func() []simulation.Team {
+		var picked []simulation.Team
+		for _, team := range teams {
+			if selected[team.UUID] {
+				picked = append(picked, team)
+			}
+		}
+		return picked
+	}
+
+	return container.NewVBox(widget.NewLabel("Select Teams:"), checkboxes, newTeamButton), selectedTeams
+}
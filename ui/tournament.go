@@ -0,0 +1,267 @@
+package ui
+// import some stuff
+import (
+	"fmt"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"hareandtortoise/v2/settings"
+	"hareandtortoise/v2/simulation"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Tournaments is the tournaments tab, pick a tournament to see its bracket and run its heats
+func Tournaments(myWindow fyne.Window) fyne.CanvasObject {
+	var tournaments []*simulation.Tournament
+	var current *simulation.Tournament
+
+	tournamentSelect := widget.NewSelect(nil, nil)
+	tournamentSelect.PlaceHolder = "Select a tournament..."
+	statusLabel := widget.NewLabel("")
+	bracket := container.NewHBox()
+
+	// show redraws the bracket for the selected tournament
+	show := func() {
+		bracket.RemoveAll()
+		if current == nil {
+			statusLabel.SetText("")
+			return
+		}
+		for r, round := range current.Rounds {
+			bracket.Add(roundColumn(current, r, round, myWindow))
+		}
+		if current.Finished() {
+			statusLabel.SetText(fmt.Sprintf("Winner: %s", current.EntrantName(current.Winner)))
+		} else if round, heat, ok := current.NextHeat(); ok {
+			statusLabel.SetText(fmt.Sprintf("Next up: %s heat %d", current.Rounds[round].Name, heat+1))
+		}
+	}
+
+	// reload the saved tournaments, keeping the selection if it is still there
+	refresh := func() {
+		var err error
+		tournaments, err = simulation.LoadTournaments()
+		if err != nil {
+			dialog.ShowError(err, myWindow)
+			return
+		}
+		labels := make([]string, len(tournaments))
+		for i, t := range tournaments {
+			labels[i] = tournamentLabel(t)
+		}
+		tournamentSelect.Options = labels
+		if current != nil {
+			for _, t := range tournaments {
+				if t.UUID == current.UUID {
+					current = t
+				}
+			}
+		}
+		tournamentSelect.Refresh()
+		show()
+	}
+	tournamentSelect.OnChanged = func(selected string) {
+		for _, t := range tournaments {
+			if tournamentLabel(t) == selected {
+				current = t
+			}
+		}
+		show()
+	}
+	refresh()
+
+	refreshButton := widget.NewButtonWithIcon("", theme.ViewRefreshIcon(), refresh)
+	newButton := widget.NewButtonWithIcon("New Tournament", theme.ContentAddIcon(), func() {
+		ShowNewTournament(fyne.CurrentApp(), func(t *simulation.Tournament) {
+			refresh()
+			tournamentSelect.SetSelected(tournamentLabel(t))
+		})
+	})
+
+	runButton := widget.NewButtonWithIcon("Run Next Heat", theme.MediaPlayIcon(), func() {
+		if current == nil {
+			dialog.ShowInformation("Error", "Please select a tournament.", myWindow)
+			return
+		}
+		tiePolicy, tick := leagueSettings()
+		err := simulation.RunHeat(fyne.CurrentApp(), current, tiePolicy, tick, func(err error) {
+			if err != nil {
+				dialog.ShowError(err, myWindow)
+			}
+			show()
+		})
+		if err != nil {
+			dialog.ShowError(err, myWindow)
+		}
+	})
+	simulateButton := widget.NewButtonWithIcon("Simulate Remaining", theme.MediaFastForwardIcon(), func() {
+		if current == nil {
+			dialog.ShowInformation("Error", "Please select a tournament.", myWindow)
+			return
+		}
+		if err := current.SimulateRemainingHeats(leagueSettings()); err != nil {
+			dialog.ShowError(err, myWindow)
+		}
+		show()
+	})
+
+	top := container.NewVBox(
+		container.NewBorder(nil, nil, nil, container.NewHBox(refreshButton, newButton), tournamentSelect),
+		container.NewHBox(runButton, simulateButton, statusLabel),
+	)
+	return container.NewBorder(top, nil, nil, nil, container.NewScroll(bracket))
+}
+
+// leagueSettings is the tie policy and race speed from the settings, heats
+// are run with them like any other race
+func leagueSettings() (string, time.Duration) {
+	existingSettings, _ := settings.LoadSettings()
+	return existingSettings.TiePolicy, time.Duration(existingSettings.TickMillis) * time.Millisecond
+}
+
+// tournamentLabel is how a tournament is listed in the dropdown
+func tournamentLabel(t *simulation.Tournament) string {
+	return fmt.Sprintf("%s - %s (%d animals)", t.Created, t.Name, len(t.Entrants))
+}
+
+// roundColumn draws one stage of the bracket, each heat lists its animals in
+// finishing order once run with the ones going through marked
+func roundColumn(t *simulation.Tournament, r int, round simulation.TournamentRound, window fyne.Window) fyne.CanvasObject {
+	seeds := make(map[string]int)
+	for _, entrant := range t.Entrants {
+		seeds[entrant.UUID] = entrant.Seed
+	}
+	final := r == len(t.Rounds)-1 && len(round.Heats) == 1
+
+	column := container.NewVBox(widget.NewLabelWithStyle(round.Name, fyne.TextAlignCenter, fyne.TextStyle{Bold: true}))
+	for h, heat := range round.Heats {
+		heatBox := container.NewVBox()
+		animals := heat.Entrants
+		if heat.Done() {
+			animals = heat.Finishers
+		}
+		for k, animal := range animals {
+			line := fmt.Sprintf("%s (seed %d)", t.EntrantName(animal), seeds[animal])
+			if heat.Done() {
+				line = simulation.FormatPlace(k+1, false) + " " + line
+				if k == 0 && final {
+					line += " - winner"
+				} else if k < simulation.HeatQualifiers && !final {
+					line += " - through"
+				}
+			}
+			heatBox.Add(widget.NewLabel(line))
+		}
+		if heat.Done() {
+			// link to the heat's saved race
+			raceUUID := heat.RaceUUID
+			heatBox.Add(container.NewHBox(
+				widget.NewLabel("Race "+raceUUID[:8]),
+				widget.NewButtonWithIcon("", theme.MediaPlayIcon(), func() {
+					if err := simulation.ShowReplayWindow(fyne.CurrentApp(), raceUUID); err != nil {
+						dialog.ShowError(err, window)
+					}
+				}),
+			))
+		}
+		column.Add(widget.NewCard("", fmt.Sprintf("Heat %d", h+1), heatBox))
+	}
+	return column
+}
+
+// ShowNewTournament lets the user pick the animals and settings for a new
+// tournament, onCreated gets it once it has been saved
+func ShowNewTournament(app fyne.App, onCreated func(*simulation.Tournament)) {
+	tournamentWindow := app.NewWindow("New Tournament")
+
+	players, err := ReadCSV("data/animal.simulation")
+	if err != nil {
+		dialog.ShowError(err, tournamentWindow)
+		return
+	}
+
+	nameEntry := widget.NewEntry()
+	nameEntry.SetPlaceHolder("Tournament name")
+	raceLengthEntry := newNumericalEntry()
+	raceLengthEntry.SetPlaceHolder("Race length for every heat")
+	modelSelect := widget.NewSelect(simulation.MovementModelNames(), nil)
+	modelSelect.SetSelected(simulation.DefaultMovementModel)
+	seedingSelect := widget.NewSelect(simulation.TournamentSeedings, nil)
+	seedingSelect.SetSelected(simulation.SeedingScore)
+
+	sizes := make([]string, len(simulation.TournamentSizes))
+	for i, size := range simulation.TournamentSizes {
+		sizes[i] = strconv.Itoa(size)
+	}
+	sizeSelect := widget.NewSelect(sizes, nil)
+	sizeSelect.SetSelected(sizes[0])
+
+	checks := make([]*widget.Check, len(players))
+	animalCheckboxes := make([]fyne.CanvasObject, len(players))
+	for i, player := range players {
+		checks[i] = widget.NewCheck(fmt.Sprintf("%s (%g)", player.Name, player.Score), nil)
+		animalCheckboxes[i] = checks[i]
+	}
+
+	// tick the best animals on the leaderboard to fill the bracket
+	pickTopButton := widget.NewButton("Pick Top Animals", func() {
+		size, _ := strconv.Atoi(sizeSelect.Selected)
+		order := make([]int, len(players))
+		for i := range order {
+			order[i] = i
+		}
+		sort.SliceStable(order, func(a, b int) bool {
+			return players[order[a]].Score > players[order[b]].Score
+		})
+		for k, i := range order {
+			checks[i].SetChecked(k < size)
+		}
+	})
+
+	createButton := widget.NewButtonWithIcon("Create", theme.ConfirmIcon(), func() {
+		var animals []simulation.Player
+		for i, check := range checks {
+			if check.Checked {
+				animals = append(animals, simulation.Player{Name: players[i].Name, UUID: players[i].UUID, Score: players[i].Score})
+			}
+		}
+		size, _ := strconv.Atoi(sizeSelect.Selected)
+		if len(animals) != size {
+			dialog.ShowInformation("Error", fmt.Sprintf("Please pick %d animals, %d are picked.", size, len(animals)), tournamentWindow)
+			return
+		}
+		raceLength, _ := strconv.Atoi(raceLengthEntry.Text)
+		t, err := simulation.NewTournament(nameEntry.Text, animals, seedingSelect.Selected, raceLength, modelSelect.Selected)
+		if err != nil {
+			dialog.ShowError(err, tournamentWindow)
+			return
+		}
+		if err := t.Save(); err != nil {
+			dialog.ShowError(err, tournamentWindow)
+			return
+		}
+		onCreated(t)
+		tournamentWindow.Close()
+	})
+
+	form := container.NewVBox(
+		nameEntry,
+		widget.NewLabel("Race Length (meters):"),
+		raceLengthEntry,
+		widget.NewLabel("Movement Model:"),
+		modelSelect,
+		widget.NewLabel("Seeding:"),
+		seedingSelect,
+		widget.NewLabel("Animals:"),
+		container.NewHBox(sizeSelect, pickTopButton),
+	)
+	content := container.NewBorder(form, createButton, nil, nil, container.NewVScroll(container.NewVBox(animalCheckboxes...)))
+	tournamentWindow.SetContent(content)
+	tournamentWindow.Resize(fyne.NewSize(400, 600))
+	tournamentWindow.CenterOnScreen()
+	tournamentWindow.Show()
+}
@@ -0,0 +1,151 @@
+package ui
+// import some stuff
+import (
+	"fmt"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"hareandtortoise/v2/simulation"
+	"strconv"
+)
+
+// segmentRow is one segment's inputs in the track builder
+type segmentRow struct {
+	terrain   *widget.Select
+	length    *widget.Entry
+	speed     *widget.Entry
+	endurance *widget.Entry
+}
+
+// segment reads the row back into a track segment
+func (row segmentRow) segment(n int) (simulation.Segment, error) {
+	segment := simulation.Segment{Terrain: row.terrain.Selected}
+	var err error
+	for _, field := range []struct {
+		name  string
+		entry *widget.Entry
+		value *float64
+	}{
+		{"length", row.length, &segment.Length},
+		{"speed", row.speed, &segment.Speed},
+		{"endurance", row.endurance, &segment.Endurance},
+	} {
+		*field.value, err = strconv.ParseFloat(field.entry.Text, 64)
+		if err != nil {
+			return segment, fmt.Errorf("segment %d: please enter a valid %s", n, field.name)
+		}
+	}
+	return segment, nil
+}
+
+// ShowCreateTrack lets the user build a track from segments and save it to
+// the library, picking a terrain fills in its usual multipliers
+func ShowCreateTrack(app fyne.App, onCreated func()) {
+	trackWindow := app.NewWindow("New Track")
+
+	trackName := widget.NewEntry()
+	trackName.SetPlaceHolder("Track name")
+
+	var rows []segmentRow
+	segmentList := container.NewVBox()
+	addSegment := func() {
+		row := segmentRow{length: widget.NewEntry(), speed: widget.NewEntry(), endurance: widget.NewEntry()}
+		row.length.SetText("1")
+		row.terrain = widget.NewSelect(simulation.TerrainTypes, func(selected string) {
+			if segment, err := simulation.NewSegment(selected, 1); err == nil {
+				row.speed.SetText(strconv.FormatFloat(segment.Speed, 'f', -1, 64))
+				row.endurance.SetText(strconv.FormatFloat(segment.Endurance, 'f', -1, 64))
+			}
+		})
+		row.terrain.SetSelected(simulation.TerrainFlat)
+		rows = append(rows, row)
+		segmentList.Add(container.NewGridWithColumns(4, row.terrain, row.length, row.speed, row.endurance))
+	}
+	addSegment()
+
+	addButton := widget.NewButtonWithIcon("Add Segment", theme.ContentAddIcon(), addSegment)
+	removeButton := widget.NewButtonWithIcon("Remove Last", theme.ContentRemoveIcon(), func() {
+		if len(rows) <= 1 {
+			return
+		}
+		rows = rows[:len(rows)-1]
+		segmentList.Remove(segmentList.Objects[len(segmentList.Objects)-1])
+	})
+
+	saveButton := widget.NewButtonWithIcon("Save", theme.ConfirmIcon(), func() {
+		track := simulation.Track{Name: trackName.Text}
+		for i, row := range rows {
+			segment, err := row.segment(i + 1)
+			if err != nil {
+				dialog.ShowError(err, trackWindow)
+				return
+			}
+			track.Segments = append(track.Segments, segment)
+		}
+		if err := simulation.SaveTrack(simulation.TracksFile, track); err != nil {
+			dialog.ShowError(err, trackWindow)
+			return
+		}
+		if onCreated != nil {
+			onCreated()
+		}
+		trackWindow.Close()
+	})
+
+	header := container.NewGridWithColumns(4, widget.NewLabel("Terrain"), widget.NewLabel("Length"), widget.NewLabel("Speed x"), widget.NewLabel("Endurance x"))
+	content := container.NewVBox(
+		trackName,
+		widget.NewLabel("Segments, in order from the start line. Lengths are shares of the race:"),
+		header,
+		segmentList,
+		container.NewHBox(addButton, removeButton),
+		saveButton,
+	)
+	trackWindow.SetContent(container.NewVScroll(content))
+	trackWindow.Resize(fyne.NewSize(500, 400))
+	trackWindow.CenterOnScreen()
+	trackWindow.Show()
+}
+
+// trackPicker shows a dropdown of the saved tracks with a button to make a
+// new one, the returned func gives the picked track
+func trackPicker(app fyne.App, window fyne.Window) (*fyne.Container, func() simulation.Track) {
+	var tracks []simulation.Track
+	trackSelect := widget.NewSelect(nil, nil)
+
+	// reload the library so a newly made track shows up
+	refresh := func() {
+		var err error
+		tracks, err = simulation.ReadTracks(simulation.TracksFile)
+		if err != nil {
+			dialog.ShowError(err, window)
+		}
+		names := []string{simulation.FlatTrack}
+		for _, track := range tracks {
+			names = append(names, track.Name)
+		}
+		trackSelect.Options = names
+		if trackSelect.Selected == "" {
+			trackSelect.SetSelected(simulation.FlatTrack)
+		}
+		trackSelect.Refresh()
+	}
+	refresh()
+
+	newTrackButton := widget.NewButtonWithIcon("New Track", theme.ContentAddIcon(), func() {
+		ShowCreateTrack(app, refresh)
+	})
+
+	selectedTrack := func() simulation.Track {
+		for _, track := range tracks {
+			if track.Name == trackSelect.Selected {
+				return track
+			}
+		}
+		return simulation.Track{}
+	}
+
+	return container.NewBorder(nil, nil, nil, newTrackButton, trackSelect), selectedTrack
+}